@@ -1,7 +1,8 @@
 package boardLabels
 
 import (
-	constants "gitlab-issue-automation/constants"
+	"fmt"
+	config "gitlab-issue-automation/config"
 	dateUtils "gitlab-issue-automation/date_utils"
 	gitlabUtils "gitlab-issue-automation/gitlab_utils"
 	"log"
@@ -33,7 +34,7 @@ func HasLabel(issue *gitlab.Issue, wantedLabel string) bool {
 	return labelPresent
 }
 
-func removeLabel(issue *gitlab.Issue, unwantedLabel string) *gitlab.Issue {
+func removeLabel(issue *gitlab.Issue, unwantedLabel string) (*gitlab.Issue, error) {
 	action := "Removing"
 	preposition := "from"
 	updatedLabels := gitlab.Labels{}
@@ -45,14 +46,14 @@ func removeLabel(issue *gitlab.Issue, unwantedLabel string) *gitlab.Issue {
 	return adaptLabel(issue, unwantedLabel, updatedLabels, action, preposition)
 }
 
-func addLabel(issue *gitlab.Issue, label string) *gitlab.Issue {
+func addLabel(issue *gitlab.Issue, label string) (*gitlab.Issue, error) {
 	action := "Adding"
 	preposition := "to"
 	updatedLabels := append(issue.Labels, label)
 	return adaptLabel(issue, label, updatedLabels, action, preposition)
 }
 
-func adaptLabel(issue *gitlab.Issue, label string, updatedLabels gitlab.Labels, action string, preposition string) *gitlab.Issue {
+func adaptLabel(issue *gitlab.Issue, label string, updatedLabels gitlab.Labels, action string, preposition string) (*gitlab.Issue, error) {
 	issueName := "'" + issue.Title + "'"
 	labelName := "'" + label + "'"
 	log.Println("-", action, "label", labelName, preposition, "issue", issueName)
@@ -63,62 +64,106 @@ func adaptLabel(issue *gitlab.Issue, label string, updatedLabels gitlab.Labels,
 	return gitlabUtils.UpdateIssue(issue.IID, options)
 }
 
-func AdaptLabels() {
+func getIssueFailure(issue *gitlab.Issue, err error) error {
+	log.Println("-- Failed:", err)
+	return fmt.Errorf("issue #%d '%s': %w", issue.IID, issue.Title, err)
+}
+
+// Adapts labels of all due issues, a failing issue does not stop the others
+func AdaptLabels() []error {
 	orderBy := "due_date"
 	sortOrder := "asc"
 	issueState := "opened"
-	issues := gitlabUtils.GetSortedProjectIssues(orderBy, sortOrder, issueState)
+	issues, err := gitlabUtils.GetSortedProjectIssues(orderBy, sortOrder, issueState)
+	if err != nil {
+		return []error{err}
+	}
+	failures := []error{}
+	currentTime := time.Now().In(config.GetLocation())
 	for _, issue := range issues {
 		if issue.DueDate == nil {
 			continue
 		}
-		issueDueTime, err := time.Parse(dateUtils.ShortISODateLayout, issue.DueDate.String())
+		issueDueTime, err := time.ParseInLocation(dateUtils.ShortISODateLayout, issue.DueDate.String(), currentTime.Location())
 		if err != nil {
-			log.Fatal(err)
+			failures = append(failures, getIssueFailure(issue, err))
+			continue
 		}
 		issueDueWeekStart := dateUtils.GetStartOfWeek(issueDueTime)
-		currentWeekStart := dateUtils.GetStartOfWeek(time.Now())
-		issuePastDue := issueDueTime.Before(time.Now())
-		issueDueToday := dateUtils.AreDatesEqual(issueDueTime, time.Now())
+		currentWeekStart := dateUtils.GetStartOfWeek(currentTime)
+		issuePastDue := issueDueTime.Before(currentTime)
+		issueDueToday := dateUtils.AreDatesEqual(issueDueTime, currentTime)
 		issueDueThisWeek := dateUtils.AreDatesEqual(issueDueWeekStart, currentWeekStart)
 		if !(issuePastDue || issueDueToday || issueDueThisWeek) {
 			break
 		}
-		issueHasProgressLabel := hasAnyLabel(issue, constants.ProgressLabels)
-		if !issueHasProgressLabel {
-			issueHasTodayLabel := HasLabel(issue, constants.TodayLabel)
-			issueHasThisWeekLabel := HasLabel(issue, constants.ThisWeekLabel)
-			issueHasNextActionsLabel := HasLabel(issue, constants.NextActionsLabel)
-			if (issuePastDue || issueDueToday) && !issueHasTodayLabel {
-				issue = addLabel(issue, constants.TodayLabel)
-				issueHasTodayLabel = true
-				if issueHasThisWeekLabel {
-					removeLabel(issue, constants.ThisWeekLabel)
+		err = adaptIssueLabels(issue, issuePastDue || issueDueToday, issueDueThisWeek)
+		if err != nil {
+			failures = append(failures, getIssueFailure(issue, err))
+		}
+	}
+	return failures
+}
+
+func adaptIssueLabels(issue *gitlab.Issue, issueDueToday bool, issueDueThisWeek bool) error {
+	var err error
+	labels := config.Get().Labels
+	issueHasProgressLabel := hasAnyLabel(issue, config.Get().ProgressLabels)
+	if !issueHasProgressLabel {
+		issueHasTodayLabel := HasLabel(issue, labels.Today)
+		issueHasThisWeekLabel := HasLabel(issue, labels.ThisWeek)
+		issueHasNextActionsLabel := HasLabel(issue, labels.NextActions)
+		if issueDueToday && !issueHasTodayLabel {
+			issue, err = addLabel(issue, labels.Today)
+			if err != nil {
+				return err
+			}
+			issueHasTodayLabel = true
+			if issueHasThisWeekLabel {
+				issue, err = removeLabel(issue, labels.ThisWeek)
+				if err != nil {
+					return err
 				}
-			} else if !issueHasTodayLabel && issueDueThisWeek && !issueHasThisWeekLabel {
-				issue = addLabel(issue, constants.ThisWeekLabel)
-				issueHasThisWeekLabel = true
 			}
-			if (issueHasTodayLabel || issueHasThisWeekLabel) && issueHasNextActionsLabel {
-				removeLabel(issue, constants.NextActionsLabel)
+		} else if !issueHasTodayLabel && issueDueThisWeek && !issueHasThisWeekLabel {
+			issue, err = addLabel(issue, labels.ThisWeek)
+			if err != nil {
+				return err
 			}
+			issueHasThisWeekLabel = true
+		}
+		if (issueHasTodayLabel || issueHasThisWeekLabel) && issueHasNextActionsLabel {
+			_, err = removeLabel(issue, labels.NextActions)
 		}
 	}
+	return err
 }
 
-func CleanLabels(lastRunTime time.Time) {
+// Removes status labels from issues closed since the last run, a failing
+// issue does not stop the others
+func CleanLabels(lastRunTime time.Time) []error {
 	orderBy := "updated_at"
 	sortOrder := "desc"
 	issueState := "closed"
-	issues := gitlabUtils.GetSortedProjectIssues(orderBy, sortOrder, issueState)
+	issues, err := gitlabUtils.GetSortedProjectIssues(orderBy, sortOrder, issueState)
+	if err != nil {
+		return []error{err}
+	}
+	failures := []error{}
 	for _, issue := range issues {
 		if issue.UpdatedAt.Before(lastRunTime) {
 			break
 		}
-		for _, statusLabel := range constants.StatusLabels {
+		for _, statusLabel := range config.Get().StatusLabels {
 			if HasLabel(issue, statusLabel) {
-				removeLabel(issue, statusLabel)
+				updatedIssue, err := removeLabel(issue, statusLabel)
+				if err != nil {
+					failures = append(failures, getIssueFailure(issue, err))
+					break
+				}
+				issue = updatedIssue
 			}
 		}
 	}
+	return failures
 }
@@ -0,0 +1,138 @@
+package boardLabels
+
+import (
+	config "gitlab-issue-automation/config"
+	constants "gitlab-issue-automation/constants"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func getDueDate(daysFromNow int) *gitlab.ISOTime {
+	dueDate := gitlab.ISOTime(time.Now().AddDate(0, 0, daysFromNow))
+	return &dueDate
+}
+
+func getSortedLabels(issue *gitlab.Issue) []string {
+	labels := append([]string{}, issue.Labels...)
+	sort.Strings(labels)
+	return labels
+}
+
+func TestAdaptLabels(t *testing.T) {
+	tests := []struct {
+		name  string
+		issue *gitlab.Issue
+		want  []string
+	}{
+		{
+			name:  "Adds today label to overdue issue",
+			issue: &gitlab.Issue{Title: "Overdue", DueDate: getDueDate(-2)},
+			want:  []string{constants.TodayLabel},
+		},
+		{
+			name:  "Replaces this week label with today label",
+			issue: &gitlab.Issue{Title: "Due today", DueDate: getDueDate(0), Labels: gitlab.Labels{constants.ThisWeekLabel}},
+			want:  []string{constants.TodayLabel},
+		},
+		{
+			name:  "Removes next actions label when due",
+			issue: &gitlab.Issue{Title: "Next action", DueDate: getDueDate(-1), Labels: gitlab.Labels{constants.NextActionsLabel}},
+			want:  []string{constants.TodayLabel},
+		},
+		{
+			name:  "Keeps labels of issues in progress",
+			issue: &gitlab.Issue{Title: "In progress", DueDate: getDueDate(-1), Labels: gitlab.Labels{constants.InProgressLabel}},
+			want:  []string{constants.InProgressLabel},
+		},
+		{
+			name:  "Keeps labels of issues due later",
+			issue: &gitlab.Issue{Title: "Later", DueDate: getDueDate(30), Labels: gitlab.Labels{constants.SomewhenLabel}},
+			want:  []string{constants.SomewhenLabel},
+		},
+		{
+			name:  "Keeps labels of issues without due date",
+			issue: &gitlab.Issue{Title: "No due date", Labels: gitlab.Labels{constants.SomewhenLabel}},
+			want:  []string{constants.SomewhenLabel},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := gitlabUtils.NewMemoryTracker()
+			gitlabUtils.SetTracker(tracker)
+			defer gitlabUtils.SetTracker(nil)
+			issue := tracker.AddIssue(tt.issue)
+			AdaptLabels()
+			got := getSortedLabels(tracker.GetIssue(issue.IID))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AdaptLabels() labels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdaptLabelsWithConfig(t *testing.T) {
+	projectConfig, err := config.Parse([]byte(`
+labels:
+  today: "status::today"
+  thisWeek: "status::this week"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Set(projectConfig)
+	defer config.Set(nil)
+	tracker := gitlabUtils.NewMemoryTracker()
+	gitlabUtils.SetTracker(tracker)
+	defer gitlabUtils.SetTracker(nil)
+	issue := tracker.AddIssue(&gitlab.Issue{Title: "Due today", DueDate: getDueDate(0), Labels: gitlab.Labels{"status::this week"}})
+
+	AdaptLabels()
+	got := getSortedLabels(tracker.GetIssue(issue.IID))
+	if !reflect.DeepEqual(got, []string{"status::today"}) {
+		t.Errorf("AdaptLabels() labels = %v, want configured today label", got)
+	}
+}
+
+func TestCleanLabels(t *testing.T) {
+	lastRunTime := time.Now().Add(-time.Hour)
+	updatedBefore := lastRunTime.Add(-time.Hour)
+	tests := []struct {
+		name  string
+		issue *gitlab.Issue
+		want  []string
+	}{
+		{
+			name:  "Removes status labels from recently closed issue",
+			issue: &gitlab.Issue{Title: "Closed", State: "closed", Labels: gitlab.Labels{constants.TodayLabel, constants.InProgressLabel, constants.SomewhenLabel}},
+			want:  []string{constants.SomewhenLabel},
+		},
+		{
+			name:  "Keeps labels of issues closed before last run",
+			issue: &gitlab.Issue{Title: "Closed before", State: "closed", UpdatedAt: &updatedBefore, Labels: gitlab.Labels{constants.TodayLabel}},
+			want:  []string{constants.TodayLabel},
+		},
+		{
+			name:  "Keeps labels of open issues",
+			issue: &gitlab.Issue{Title: "Open", Labels: gitlab.Labels{constants.TodayLabel}},
+			want:  []string{constants.TodayLabel},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := gitlabUtils.NewMemoryTracker()
+			gitlabUtils.SetTracker(tracker)
+			defer gitlabUtils.SetTracker(nil)
+			issue := tracker.AddIssue(tt.issue)
+			CleanLabels(lastRunTime)
+			got := getSortedLabels(tracker.GetIssue(issue.IID))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CleanLabels() labels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
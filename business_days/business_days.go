@@ -0,0 +1,125 @@
+package businessDays
+
+import (
+	"errors"
+	"fmt"
+	config "gitlab-issue-automation/config"
+	"gitlab-issue-automation/constants"
+	dateUtils "gitlab-issue-automation/date_utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Holidays that occur every year use the placeholder YEAR, e.g. YEAR-12-25
+const yearPlaceholder = "YEAR"
+
+// Calendar of the workdays of the configured workweek without holidays
+type Calendar struct {
+	workdays       map[time.Weekday]bool
+	holidays       map[string]bool
+	yearlyHolidays map[string]bool
+}
+
+func parseHolidays(contents []byte) (map[string]bool, map[string]bool, error) {
+	dates := []string{}
+	err := yaml.Unmarshal(contents, &dates)
+	if err != nil {
+		return nil, nil, err
+	}
+	holidays := map[string]bool{}
+	yearlyHolidays := map[string]bool{}
+	for _, date := range dates {
+		if strings.HasPrefix(date, yearPlaceholder+"-") {
+			monthDay := strings.TrimPrefix(date, yearPlaceholder+"-")
+			// Validated with a leap year to allow February 29
+			_, err = time.Parse(dateUtils.ShortISODateLayout, "2024-"+monthDay)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid holiday '%s', use YYYY-MM-DD or YEAR-MM-DD", date)
+			}
+			yearlyHolidays[monthDay] = true
+			continue
+		}
+		_, err = time.Parse(dateUtils.ShortISODateLayout, date)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid holiday '%s', use YYYY-MM-DD or YEAR-MM-DD", date)
+		}
+		holidays[date] = true
+	}
+	return holidays, yearlyHolidays, nil
+}
+
+// Loads the workweek of the config and the holidays file of the templates
+// directory, which is optional
+func Load(templatesPath string) (*Calendar, error) {
+	calendar := &Calendar{workdays: map[time.Weekday]bool{}, holidays: map[string]bool{}, yearlyHolidays: map[string]bool{}}
+	for _, name := range config.Get().Workweek {
+		weekday, err := dateUtils.ParseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+		calendar.workdays[weekday] = true
+	}
+	if len(calendar.workdays) == 0 {
+		return nil, errors.New("the workweek has no workdays")
+	}
+	holidaysPath := filepath.Join(templatesPath, constants.HolidaysFileName)
+	contents, err := ioutil.ReadFile(holidaysPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return calendar, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	calendar.holidays, calendar.yearlyHolidays, err = parseHolidays(contents)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", holidaysPath, err)
+	}
+	return calendar, nil
+}
+
+// Days are checked in the timezone of the given time
+func (c *Calendar) IsWorkday(day time.Time) bool {
+	date := day.Format(dateUtils.ShortISODateLayout)
+	return c.workdays[day.Weekday()] && !c.holidays[date] && !c.yearlyHolidays[date[5:]]
+}
+
+// Moves a time that is not on a workday to the same time of the next or
+// previous workday
+func (c *Calendar) Shift(day time.Time, shift string) time.Time {
+	step := 1
+	switch shift {
+	case constants.ShiftNext:
+	case constants.ShiftPrevious:
+		step = -1
+	default:
+		return day
+	}
+	for !c.IsWorkday(day) {
+		day = day.AddDate(0, 0, step)
+	}
+	return day
+}
+
+// Adds the given number of workdays, keeping the time of day
+func (c *Calendar) AddWorkdays(day time.Time, workdays int) time.Time {
+	for workdays > 0 {
+		day = day.AddDate(0, 0, 1)
+		if c.IsWorkday(day) {
+			workdays--
+		}
+	}
+	return day
+}
+
+func ValidateShift(shift string) error {
+	switch shift {
+	case constants.ShiftNext, constants.ShiftPrevious, constants.ShiftNone, "":
+		return nil
+	}
+	return fmt.Errorf("unknown shift '%s', use one of %s, %s, or %s", shift, constants.ShiftNext, constants.ShiftPrevious, constants.ShiftNone)
+}
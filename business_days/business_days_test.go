@@ -0,0 +1,70 @@
+package businessDays
+
+import (
+	config "gitlab-issue-automation/config"
+	"gitlab-issue-automation/constants"
+	types "gitlab-issue-automation/types"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeHolidays(t *testing.T, contents string) string {
+	templatesPath := t.TempDir()
+	err := os.WriteFile(filepath.Join(templatesPath, constants.HolidaysFileName), []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return templatesPath
+}
+
+func TestCalendar(t *testing.T) {
+	calendar, err := Load(writeHolidays(t, `["2026-04-03", "YEAR-12-25"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Thursday before Good Friday
+	thursday := time.Date(2026, 4, 2, 9, 0, 0, 0, time.UTC)
+	christmasDay := time.Date(2027, 12, 25, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{name: "Keeps workdays", got: calendar.Shift(thursday, constants.ShiftNext), want: thursday},
+		{name: "Shifts holidays to next workday", got: calendar.Shift(thursday.AddDate(0, 0, 1), constants.ShiftNext), want: thursday.AddDate(0, 0, 4)},
+		{name: "Shifts weekends to previous workday", got: calendar.Shift(thursday.AddDate(0, 0, 3), constants.ShiftPrevious), want: thursday},
+		{name: "Does not shift for none", got: calendar.Shift(thursday.AddDate(0, 0, 2), constants.ShiftNone), want: thursday.AddDate(0, 0, 2)},
+		{name: "Shifts yearly holidays", got: calendar.Shift(christmasDay.AddDate(0, 0, -1), constants.ShiftNext), want: christmasDay.AddDate(0, 0, -1)},
+		{name: "Shifts yearly holidays in every year", got: calendar.Shift(christmasDay, constants.ShiftPrevious), want: christmasDay.AddDate(0, 0, -1)},
+		{name: "Adds workdays over weekend and holiday", got: calendar.AddWorkdays(thursday, 2), want: thursday.AddDate(0, 0, 5)},
+		{name: "Adds no workdays", got: calendar.AddWorkdays(thursday, 0), want: thursday},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defer config.Set(nil)
+	config.Set(&types.Config{Workweek: []string{"Sunday", "monday"}})
+	calendar, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sunday := time.Date(2026, 1, 4, 9, 0, 0, 0, time.UTC)
+	if !calendar.IsWorkday(sunday) || !calendar.IsWorkday(sunday.AddDate(0, 0, 1)) || calendar.IsWorkday(sunday.AddDate(0, 0, 2)) {
+		t.Errorf("Load() did not use the configured workweek")
+	}
+
+	config.Set(nil)
+	_, err = Load(writeHolidays(t, `["2026-13-01"]`))
+	if err == nil {
+		t.Error("Load() should fail for invalid holidays")
+	}
+}
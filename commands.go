@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	boardLabels "gitlab-issue-automation/board_labels"
+	config "gitlab-issue-automation/config"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	planMode "gitlab-issue-automation/plan_mode"
+	recurranceExceptions "gitlab-issue-automation/recurrance_exceptions"
+	recurringIssues "gitlab-issue-automation/recurring_issues"
+	standupNotes "gitlab-issue-automation/standup_notes"
+	stateStore "gitlab-issue-automation/state_store"
+	types "gitlab-issue-automation/types"
+	"log"
+	"os"
+	"time"
+)
+
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	common := addCommonFlags(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = common.apply()
+	if err != nil {
+		return err
+	}
+	return config.Load(gitlabUtils.GetConfigPath())
+}
+
+func startPlanMode(plan bool) *planMode.PlanTracker {
+	if !plan {
+		return nil
+	}
+	log.Println("Running in plan mode, no changes will be applied")
+	planTracker := planMode.NewPlanTracker(gitlabUtils.GetTracker())
+	gitlabUtils.SetTracker(planTracker)
+	return planTracker
+}
+
+// The diff goes to the log, so only the JSON is written to stdout and can be
+// piped to other tools
+func printPlan(planTracker *planMode.PlanTracker) error {
+	log.Println("Planned changes:")
+	planTracker.PrintDiff(os.Stderr)
+	return planTracker.PrintJSON(os.Stdout)
+}
+
+func loadState() (stateStore.Store, *types.RunState, error) {
+	store, err := stateStore.GetStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := stateStore.LoadState(store)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Println("Last run:", state.LastRunTime.Format(time.RFC3339))
+	return store, state, nil
+}
+
+func runCommand(flags *flag.FlagSet, args []string) []error {
+	planFlag := flags.Bool("plan", false, "Print planned changes instead of applying them (default $PLAN_MODE)")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return []error{err}
+	}
+	return runStages(*planFlag || gitlabUtils.GetPlanMode())
+}
+
+func planCommand(flags *flag.FlagSet, args []string) []error {
+	err := parseFlags(flags, args)
+	if err != nil {
+		return []error{err}
+	}
+	return runStages(true)
+}
+
+// Keys of the stages in the failed entries of the state, which cannot clash
+// with templates since those end with .md
+const cleanLabelsStage = "stage:clean-labels"
+const standupNotesStage = "stage:standup-notes"
+
+func runStages(plan bool) []error {
+	planTracker := startPlanMode(plan)
+	store, state, err := loadState()
+	if err != nil {
+		return []error{err}
+	}
+	lastRunTime := state.LastRunTime
+	runTime := time.Now()
+	failures := []error{}
+	log.Println("Checking whether to create recurring issues")
+	failures = append(failures, recurringIssues.ProcessIssueFiles(lastRunTime, state)...)
+	log.Println("Checking whether to adapt board labels")
+	failures = append(failures, boardLabels.AdaptLabels()...)
+	cleanLabelsTime := stateStore.GetRetryTime(state, cleanLabelsStage, lastRunTime)
+	labelFailures := boardLabels.CleanLabels(cleanLabelsTime)
+	var labelsErr error
+	if len(labelFailures) > 0 {
+		labelsErr = labelFailures[0]
+	}
+	stateStore.SetResult(state, cleanLabelsStage, cleanLabelsTime, labelsErr)
+	failures = append(failures, labelFailures...)
+	log.Println("Checking whether to create standup notes")
+	standupNotesTime := stateStore.GetRetryTime(state, standupNotesStage, lastRunTime)
+	err = standupNotes.WriteNotes(standupNotesTime, gitlabUtils.GetForceStandupNotesForToday())
+	stateStore.SetResult(state, standupNotesStage, standupNotesTime, err)
+	if err != nil {
+		log.Println("-- Failed:", err)
+		failures = append(failures, err)
+	}
+	if plan {
+		err = printPlan(planTracker)
+		if err != nil {
+			failures = append(failures, err)
+		}
+		return failures
+	}
+	// Failed templates and stages are retried from their entries in the state,
+	// so they do not hold back the others
+	state.LastRunTime = runTime
+	err = stateStore.SaveState(store, state)
+	if err != nil {
+		failures = append(failures, err)
+	}
+	return failures
+}
+
+func validateCommand(flags *flag.FlagSet, args []string) []error {
+	err := parseFlags(flags, args)
+	if err != nil {
+		return []error{err}
+	}
+	log.Println("Validating templates in", gitlabUtils.GetRecurringIssuesPath())
+	failures := recurringIssues.ValidateIssueFiles()
+	log.Println("Validating recurrance exceptions")
+	failures = append(failures, recurranceExceptions.Validate()...)
+	return failures
+}
+
+func nextCommand(flags *flag.FlagSet, args []string) []error {
+	count := flags.Int("count", 1, "Number of occurrences to print per template")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return []error{err}
+	}
+	_, state, err := loadState()
+	if err != nil {
+		return []error{err}
+	}
+	occurrences, failures := recurringIssues.GetNextOccurrences(time.Now(), *count, state)
+	for _, occurrence := range occurrences {
+		line := fmt.Sprintf("%s  %s  %s", occurrence.NextTime.Format(time.RFC3339), occurrence.TemplateKey, occurrence.Title)
+		if occurrence.DueIn != "" {
+			dueDate, err := gitlabUtils.GetIssueDueDate(occurrence)
+			if err == nil {
+				line += fmt.Sprintf(" (due %s)", dueDate.Format(time.RFC3339))
+			}
+		}
+		fmt.Println(line)
+	}
+	return failures
+}
+
+func standupCommand(flags *flag.FlagSet, args []string) []error {
+	force := flags.Bool("force", false, "Create standup notes for today even if they are not due (default $FORCE_STANDUP_NOTES_FOR_TODAY)")
+	plan := flags.Bool("plan", false, "Print planned changes instead of applying them (default $PLAN_MODE)")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return []error{err}
+	}
+	planTracker := startPlanMode(*plan || gitlabUtils.GetPlanMode())
+	_, state, err := loadState()
+	if err != nil {
+		return []error{err}
+	}
+	failures := []error{}
+	log.Println("Checking whether to create standup notes")
+	err = standupNotes.WriteNotes(state.LastRunTime, *force || gitlabUtils.GetForceStandupNotesForToday())
+	if err != nil {
+		failures = append(failures, err)
+	}
+	if planTracker != nil {
+		err = printPlan(planTracker)
+		if err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return failures
+}
+
+func labelsCommand(flags *flag.FlagSet, args []string) []error {
+	plan := flags.Bool("plan", false, "Print planned changes instead of applying them (default $PLAN_MODE)")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return []error{err}
+	}
+	planTracker := startPlanMode(*plan || gitlabUtils.GetPlanMode())
+	_, state, err := loadState()
+	if err != nil {
+		return []error{err}
+	}
+	log.Println("Checking whether to adapt board labels")
+	failures := boardLabels.AdaptLabels()
+	failures = append(failures, boardLabels.CleanLabels(state.LastRunTime)...)
+	if planTracker != nil {
+		err = printPlan(planTracker)
+		if err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return failures
+}
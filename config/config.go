@@ -0,0 +1,153 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gitlab-issue-automation/constants"
+	dateUtils "gitlab-issue-automation/date_utils"
+	types "gitlab-issue-automation/types"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The config is loaded once at the start of a run
+var loadedConfig *types.Config
+
+func withDefaultLabels(labels types.LabelConfig) types.LabelConfig {
+	defaults := map[*string]string{
+		&labels.ThisWeek:       constants.ThisWeekLabel,
+		&labels.Today:          constants.TodayLabel,
+		&labels.InProgress:     constants.InProgressLabel,
+		&labels.Waiting:        constants.WaitingLabel,
+		&labels.InOffice:       constants.InOfficeLabel,
+		&labels.Recurring:      constants.RecurringLabel,
+		&labels.NextActions:    constants.NextActionsLabel,
+		&labels.Somewhen:       constants.SomewhenLabel,
+		&labels.Test:           constants.TestLabel,
+		&labels.DoneThisWeek:   constants.DoneThisWeekLabel,
+		&labels.NotYet:         constants.NotYetLabel,
+		&labels.IssueReference: constants.IssueReferenceLabel,
+		&labels.Missed:         constants.MissedLabel,
+	}
+	for label, defaultLabel := range defaults {
+		if *label == "" {
+			*label = defaultLabel
+		}
+	}
+	return labels
+}
+
+// Fills in unset values, label groups default to the configured label names
+func withDefaults(config types.Config) *types.Config {
+	config.Labels = withDefaultLabels(config.Labels)
+	labels := config.Labels
+	if config.StatusLabels == nil {
+		config.StatusLabels = []string{labels.ThisWeek, labels.Today, labels.InProgress, labels.Waiting, labels.InOffice, labels.DoneThisWeek}
+	}
+	if config.ProgressLabels == nil {
+		config.ProgressLabels = []string{labels.InProgress, labels.DoneThisWeek}
+	}
+	if config.NonProjectLabels == nil {
+		config.NonProjectLabels = []string{labels.ThisWeek, labels.Today, labels.InProgress, labels.Waiting, labels.InOffice, labels.Recurring, labels.NextActions, labels.Somewhen, labels.Test, labels.DoneThisWeek, labels.NotYet, labels.IssueReference, labels.Missed}
+	}
+	if config.IssueTemplatePath == "" {
+		config.IssueTemplatePath = constants.IssueTemplatePath
+	}
+	if config.StandupTemplateName == "" {
+		config.StandupTemplateName = constants.StandupIssueTemplateName
+	}
+	if config.StandupWikiPrefix == "" {
+		config.StandupWikiPrefix = constants.StandupWikiPrefix
+	}
+	if config.Workweek == nil {
+		config.Workweek = append([]string{}, constants.DefaultWorkweek...)
+	}
+	return &config
+}
+
+func Default() *types.Config {
+	return withDefaults(types.Config{})
+}
+
+func Parse(contents []byte) (*types.Config, error) {
+	config := types.Config{}
+	err := yaml.UnmarshalStrict(contents, &config)
+	if err != nil {
+		return nil, err
+	}
+	_, err = LoadLocation(config.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	for _, weekday := range config.Workweek {
+		_, err = dateUtils.ParseWeekday(weekday)
+		if err != nil {
+			return nil, fmt.Errorf("workweek: %w", err)
+		}
+	}
+	return withDefaults(config), nil
+}
+
+// Loads an IANA timezone like Europe/Berlin, without a timezone the timezone
+// of the machine is used
+func LoadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone '%s'", timezone)
+	}
+	return location, nil
+}
+
+// Timezone of the project config, which is validated when it is loaded
+func GetLocation() *time.Location {
+	location, err := LoadLocation(Get().Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+// Timezone of the template if set, otherwise of the project config
+func GetTemplateLocation(data *types.Metadata) *time.Location {
+	if data.Location != nil {
+		return data.Location
+	}
+	return GetLocation()
+}
+
+// Reads the config at the given path, the defaults are used if it does not
+// exist
+func Load(configPath string) error {
+	contents, err := ioutil.ReadFile(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		loadedConfig = Default()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	config, err := Parse(contents)
+	if err != nil {
+		return fmt.Errorf("could not parse %s: %w", configPath, err)
+	}
+	loadedConfig = config
+	return nil
+}
+
+// Replaces the loaded config, nil restores the defaults
+func Set(config *types.Config) {
+	loadedConfig = config
+}
+
+func Get() *types.Config {
+	if loadedConfig == nil {
+		return Default()
+	}
+	return loadedConfig
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"gitlab-issue-automation/constants"
+	types "gitlab-issue-automation/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		check   func(t *testing.T, got types.Config)
+		wantErr bool
+	}{
+		{
+			name:   "Uses defaults for empty config",
+			config: "",
+			check: func(t *testing.T, got types.Config) {
+				if !reflect.DeepEqual(got, *Default()) {
+					t.Errorf("Parse() = %v, want defaults", got)
+				}
+				if got.Labels.Today != constants.TodayLabel || got.IssueTemplatePath != constants.IssueTemplatePath {
+					t.Errorf("Parse() = %v, want default labels and paths", got)
+				}
+			},
+		},
+		{
+			name: "Derives label groups from overridden labels",
+			config: `
+labels:
+  inProgress: "status::doing"
+  doneThisWeek: "status::done"
+issueTemplatePath: templates
+standupTemplateName: standup.md
+standupWikiPrefix: Standups/
+`,
+			check: func(t *testing.T, got types.Config) {
+				want := []string{"status::doing", "status::done"}
+				if !reflect.DeepEqual(got.ProgressLabels, want) {
+					t.Errorf("Parse() progress labels = %v, want %v", got.ProgressLabels, want)
+				}
+				if got.StatusLabels[2] != "status::doing" || got.Labels.Today != constants.TodayLabel {
+					t.Errorf("Parse() status labels = %v", got.StatusLabels)
+				}
+				if got.IssueTemplatePath != "templates" || got.StandupTemplateName != "standup.md" || got.StandupWikiPrefix != "Standups/" {
+					t.Errorf("Parse() = %v, want overridden paths", got)
+				}
+			},
+		},
+		{
+			name: "Keeps overridden label groups",
+			config: `
+statusLabels: ["status::doing"]
+nonProjectLabels: []
+`,
+			check: func(t *testing.T, got types.Config) {
+				if !reflect.DeepEqual(got.StatusLabels, []string{"status::doing"}) || len(got.NonProjectLabels) != 0 {
+					t.Errorf("Parse() = %v, want overridden groups", got)
+				}
+			},
+		},
+		{
+			name:   "Parses timezone",
+			config: "timezone: Europe/Berlin",
+			check: func(t *testing.T, got types.Config) {
+				if got.Timezone != "Europe/Berlin" {
+					t.Errorf("Parse() timezone = %v, want Europe/Berlin", got.Timezone)
+				}
+			},
+		},
+		{
+			name:    "Fails for unknown timezone",
+			config:  "timezone: Europe/Atlantis",
+			wantErr: true,
+		},
+		{
+			name:    "Fails for unknown workweek days",
+			config:  "workweek: [Monday, Funday]",
+			wantErr: true,
+		},
+		{
+			name:    "Fails for unknown keys",
+			config:  "lables: {}",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				tt.check(t, *got)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defer Set(nil)
+	configPath := filepath.Join(t.TempDir(), "issue-automation.yml")
+	err := Load(configPath)
+	if err != nil || !reflect.DeepEqual(Get(), Default()) {
+		t.Fatalf("Load() = %v, %v, want defaults for missing config", Get(), err)
+	}
+	err = os.WriteFile(configPath, []byte("standupWikiPrefix: Standups/"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Load(configPath)
+	if err != nil || Get().StandupWikiPrefix != "Standups/" {
+		t.Errorf("Load() = %v, %v, want config of file", Get(), err)
+	}
+}
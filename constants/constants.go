@@ -1,14 +1,55 @@
 package constants
 
+const ConfigPath = ".gitlab/issue-automation.yml"
 const IssueTemplatePath = ".gitlab/recurring_issue_templates/"
 const StandupIssueTemplateName = "prepare-standup.md" // for this template notes will be created
+const StandupWikiPrefix = "Meetings/Standup/"
+const DefaultsFileName = "_defaults.yml" // merged into all templates of its directory and subdirectories
+const HolidaysFileName = "holidays.yml"  // dates that are not workdays, in the templates directory
+const ExceptionsFileName = "recurrance_exceptions.yml"
+
+// How lists of templates are merged with lists of defaults
+
+const ListMergeAppend = "append"
+const ListMergeReplace = "replace"
+
+// Catch up policies for occurrences that were missed since the last run
+
+const CatchUpAll = "all"
+const CatchUpLatest = "latest"
+const CatchUpNone = "none"
+const MaxCatchUpOccurrences = 100
+const MaxCatchUpDays = 366
+
+// Dynamic selectors for milestones and iterations, other values are titles
+
+const CurrentTimeboxSelector = "current"
+const NextTimeboxSelector = "next"
+
+var IssueTypes = []string{"issue", "incident", "task"}
+
+const RelatesToLinkType = "relates_to"
+
+// Directions to move occurrences and due dates that are not on a workday
+
+const ShiftNext = "next"
+const ShiftPrevious = "previous"
+const ShiftNone = "none"
+
+var DefaultWorkweek = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}
+
+// Policies for the open issue of the previous occurrence
+
+const PreviousOccurrenceClose = "close"
+const PreviousOccurrenceLabel = "label"
+const PreviousOccurrenceKeep = "keep"
 
 // Vacation issue definitions
 
 const VacationTemplateName = "vacation.md"
 const VacationExceptionPrefix = "vacation-"
 
-// Label definitions
+// Default label definitions, each can be overridden in the project config
 
 const ThisWeekLabel = "🗓 This week"
 const TodayLabel = "☀️ Today"
@@ -22,7 +63,4 @@ const TestLabel = "🧪 Test"
 const DoneThisWeekLabel = "✅ Done this week"
 const NotYetLabel = "⏰ Not yet"
 const IssueReferenceLabel = "🔗 Issue reference"
-
-var ProgressLabels = []string{InProgressLabel, DoneThisWeekLabel}
-var StatusLabels = []string{ThisWeekLabel, TodayLabel, InProgressLabel, WaitingLabel, InOfficeLabel, DoneThisWeekLabel}
-var NonProjectLabels = []string{ThisWeekLabel, TodayLabel, InProgressLabel, WaitingLabel, InOfficeLabel, RecurringLabel, NextActionsLabel, SomewhenLabel, TestLabel, DoneThisWeekLabel, NotYetLabel, IssueReferenceLabel}
+const MissedLabel = "👻 Missed"
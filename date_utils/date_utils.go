@@ -1,6 +1,7 @@
 package dateUtils
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 	"time"
@@ -10,6 +11,10 @@ const ShortISODateLayout = "2006-01-02"
 
 const YearDateLayout = "2006"
 
+const ICalendarDateLayout = "20060102"
+
+const iCalendarTimeLayout = "20060102T150405"
+
 const enDash = "–"
 const dash = "-"
 
@@ -19,12 +24,41 @@ func GetStartOfWeek(thisTime time.Time) time.Time {
 	return thisDay.AddDate(0, 0, -thisWeekday)
 }
 
+// Dates are compared in the timezone of the first time
 func AreDatesEqual(aTime time.Time, anotherTime time.Time) bool {
 	aYear, aMonth, aDay := aTime.Date()
-	anotherYear, anotherMonth, anotherDay := anotherTime.Date()
+	anotherYear, anotherMonth, anotherDay := anotherTime.In(aTime.Location()).Date()
 	return aYear == anotherYear && aMonth == anotherMonth && aDay == anotherDay
 }
 
+// Parses an English weekday name like Monday, ignoring case
+func ParseWeekday(name string) (time.Weekday, error) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(weekday.String(), name) {
+			return weekday, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("unknown weekday '%s', use English names like Monday", name)
+}
+
+// Parses an iCalendar date like 20260105 or a time like 20260105T090000 in
+// the given location, or a UTC time like 20260105T090000Z
+func ParseICalendarTime(value string, location *time.Location) (time.Time, error) {
+	layouts := map[int]string{
+		len(ICalendarDateLayout):     ICalendarDateLayout,
+		len(iCalendarTimeLayout):     iCalendarTimeLayout,
+		len(iCalendarTimeLayout) + 1: iCalendarTimeLayout + "Z",
+	}
+	layout, exists := layouts[len(value)]
+	if !exists {
+		return time.Time{}, fmt.Errorf("invalid time '%s', use YYYYMMDD or YYYYMMDDTHHMMSS", value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		location = time.UTC
+	}
+	return time.ParseInLocation(layout, value, location)
+}
+
 func EscapeDashes(text string) string {
 	return strings.ReplaceAll(text, dash, enDash)
 }
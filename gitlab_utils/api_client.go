@@ -0,0 +1,232 @@
+package gitlabUtils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const defaultRetryMax = 5
+const defaultRetryWaitMin = time.Second
+const defaultRetryWaitMax = time.Minute
+
+type APIStats struct {
+	Requests         int
+	Retries          int
+	RateLimitWaits   int
+	RateLimitWaited  time.Duration
+	RequestsByMethod map[string]int
+}
+
+// Counts all requests sent to the API and waits for the rate limit to reset
+// before a request is sent if no requests are remaining
+type apiTransport struct {
+	base      http.RoundTripper
+	mutex     sync.Mutex
+	stats     APIStats
+	remaining int
+	reset     time.Time
+	sleep     func(time.Duration)
+}
+
+func newAPITransport(base http.RoundTripper) *apiTransport {
+	return &apiTransport{
+		base:      base,
+		stats:     APIStats{RequestsByMethod: map[string]int{}},
+		remaining: -1,
+		sleep:     time.Sleep,
+	}
+}
+
+func (t *apiTransport) waitForRateLimit() {
+	t.mutex.Lock()
+	wait := time.Duration(0)
+	if t.remaining == 0 {
+		wait = time.Until(t.reset)
+	}
+	if wait > 0 {
+		t.stats.RateLimitWaits++
+		t.stats.RateLimitWaited += wait
+	}
+	t.mutex.Unlock()
+	if wait > 0 {
+		log.Println("- Rate limit reached, waiting", wait.Round(time.Second))
+		t.sleep(wait)
+	}
+}
+
+func (t *apiTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.waitForRateLimit()
+	response, err := t.base.RoundTrip(request)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.stats.Requests++
+	t.stats.RequestsByMethod[request.Method]++
+	if err != nil {
+		return response, err
+	}
+	remaining, err := strconv.Atoi(response.Header.Get("RateLimit-Remaining"))
+	if err == nil {
+		t.remaining = remaining
+		reset, err := strconv.ParseInt(response.Header.Get("RateLimit-Reset"), 10, 64)
+		if err == nil {
+			t.reset = time.Unix(reset, 0)
+		}
+	}
+	return response, nil
+}
+
+// Called before each attempt of a request, only attempts after the first one
+// are retries
+func (t *apiTransport) countRetry(_ retryablehttp.Logger, _ *http.Request, attemptNum int) {
+	if attemptNum == 0 {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.stats.Retries++
+}
+
+func (t *apiTransport) getStats() APIStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	stats := t.stats
+	stats.RequestsByMethod = map[string]int{}
+	for method, count := range t.stats.RequestsByMethod {
+		stats.RequestsByMethod[method] = count
+	}
+	return stats
+}
+
+func getRetryAfter(response *http.Response) time.Duration {
+	if response == nil {
+		return 0
+	}
+	retryAfter := response.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if retryTime, err := http.ParseTime(retryAfter); err == nil {
+		return time.Until(retryTime)
+	}
+	if response.StatusCode == http.StatusTooManyRequests {
+		if reset, err := strconv.ParseInt(response.Header.Get("RateLimit-Reset"), 10, 64); err == nil {
+			return time.Until(time.Unix(reset, 0))
+		}
+	}
+	return 0
+}
+
+// Rate limited requests and requests that could not connect were not
+// processed, so they are always retried. Other server errors are only retried
+// for idempotent methods, since GitLab might have created an issue, note, or
+// link before the error.
+func checkRetry(ctx context.Context, response *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true, nil
+		}
+		return false, err
+	}
+	if response.StatusCode == http.StatusTooManyRequests {
+		return true, nil
+	}
+	return response.StatusCode >= 500 && isIdempotent(response.Request), nil
+}
+
+func isIdempotent(request *http.Request) bool {
+	if request == nil {
+		return false
+	}
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Waits as long as the server asks for, otherwise exponentially longer with
+// each attempt
+func getRetryBackoff(min time.Duration, max time.Duration, attemptNum int, response *http.Response) time.Duration {
+	retryAfter := getRetryAfter(response)
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	backoff := time.Duration(float64(min) * math.Pow(2, float64(attemptNum)))
+	if backoff > max || backoff <= 0 {
+		backoff = max
+	}
+	return backoff
+}
+
+func GetRetryMax() (int, error) {
+	retryMax := getOptionalEnvVariable("GITLAB_RETRY_MAX")
+	if retryMax == "" {
+		return defaultRetryMax, nil
+	}
+	atoi, err := strconv.Atoi(retryMax)
+	if err != nil || atoi < 0 {
+		return 0, fmt.Errorf("invalid GITLAB_RETRY_MAX '%s', use a number of retries", retryMax)
+	}
+	return atoi, nil
+}
+
+func getRetryWait(name string, defaultWait time.Duration) (time.Duration, error) {
+	wait := getOptionalEnvVariable(name)
+	if wait == "" {
+		return defaultWait, nil
+	}
+	duration, err := time.ParseDuration(wait)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s '%s', use a duration like 1s", name, wait)
+	}
+	return duration, nil
+}
+
+func GetRetryWaitMin() (time.Duration, error) {
+	return getRetryWait("GITLAB_RETRY_WAIT_MIN", defaultRetryWaitMin)
+}
+
+func GetRetryWaitMax() (time.Duration, error) {
+	return getRetryWait("GITLAB_RETRY_WAIT_MAX", defaultRetryWaitMax)
+}
+
+func GetAPIStats() APIStats {
+	if transport == nil {
+		return APIStats{RequestsByMethod: map[string]int{}}
+	}
+	return transport.getStats()
+}
+
+func LogAPIStats() {
+	stats := GetAPIStats()
+	methods := []string{}
+	for method := range stats.RequestsByMethod {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	log.Println("API calls:", stats.Requests)
+	for _, method := range methods {
+		log.Println("-", method, stats.RequestsByMethod[method])
+	}
+	if stats.Retries > 0 {
+		log.Println("- Retries:", stats.Retries)
+	}
+	if stats.RateLimitWaits > 0 {
+		log.Println("- Waited for rate limit:", stats.RateLimitWaits, "time(s),", stats.RateLimitWaited.Round(time.Second))
+	}
+}
@@ -0,0 +1,232 @@
+package gitlabUtils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestGetRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		attemptNum int
+		statusCode int
+		headers    map[string]string
+		wantMin    time.Duration
+		wantMax    time.Duration
+	}{
+		{
+			name:       "Backs off exponentially",
+			attemptNum: 3,
+			statusCode: http.StatusBadGateway,
+			wantMin:    8 * time.Second,
+			wantMax:    8 * time.Second,
+		},
+		{
+			name:       "Caps backoff",
+			attemptNum: 10,
+			statusCode: http.StatusBadGateway,
+			wantMin:    time.Minute,
+			wantMax:    time.Minute,
+		},
+		{
+			name:       "Respects Retry-After seconds",
+			statusCode: http.StatusTooManyRequests,
+			headers:    map[string]string{"Retry-After": "42"},
+			wantMin:    42 * time.Second,
+			wantMax:    42 * time.Second,
+		},
+		{
+			name:       "Respects Retry-After date",
+			statusCode: http.StatusServiceUnavailable,
+			headers:    map[string]string{"Retry-After": time.Now().Add(2 * time.Minute).UTC().Format(http.TimeFormat)},
+			wantMin:    time.Minute,
+			wantMax:    2 * time.Minute,
+		},
+		{
+			name:       "Respects RateLimit-Reset when rate limited",
+			statusCode: http.StatusTooManyRequests,
+			headers:    map[string]string{"RateLimit-Reset": strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10)},
+			wantMin:    20 * time.Second,
+			wantMax:    30 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &http.Response{StatusCode: tt.statusCode, Header: http.Header{}}
+			for name, value := range tt.headers {
+				response.Header.Set(name, value)
+			}
+			got := getRetryBackoff(time.Second, time.Minute, tt.attemptNum, response)
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("getRetryBackoff() = %v, want between %v and %v", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestGetGitProject(t *testing.T) {
+	failures := 2
+	projectRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		projectRequests++
+		if projectRequests <= failures {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(writer, `{"id": 42}`)
+	}))
+	defer server.Close()
+	t.Setenv("CI_API_V4_URL", server.URL+"/api/v4")
+	t.Setenv("CI_PROJECT_ID", "42")
+	t.Setenv("GITLAB_ISSUE_AUTOMATION_API_TOKEN", "token")
+	t.Setenv("GITLAB_RETRY_WAIT_MIN", "1ms")
+	t.Setenv("GITLAB_RETRY_WAIT_MAX", "5ms")
+	gitClient, gitProject, transport = nil, nil, nil
+	defer func() { gitClient, gitProject, transport = nil, nil, nil }()
+
+	for i := 0; i < 3; i++ {
+		project, err := GetGitProject()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.ID != 42 {
+			t.Fatalf("GetGitProject() = %v, want project 42", project)
+		}
+	}
+	stats := GetAPIStats()
+	if projectRequests != 3 || stats.Requests != 3 || stats.RequestsByMethod[http.MethodGet] != 3 {
+		t.Errorf("GetGitProject() sent %d requests, counted %v, want 3", projectRequests, stats)
+	}
+	if stats.Retries != failures {
+		t.Errorf("GetAPIStats() retried = %d, want %d", stats.Retries, failures)
+	}
+}
+
+func TestCheckRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statusCode int
+		err        error
+		want       bool
+	}{
+		{name: "Retries rate limited requests", method: http.MethodPost, statusCode: http.StatusTooManyRequests, want: true},
+		{name: "Retries idempotent requests after server errors", method: http.MethodGet, statusCode: http.StatusBadGateway, want: true},
+		{name: "Does not retry creating after server errors", method: http.MethodPost, statusCode: http.StatusGatewayTimeout},
+		{name: "Does not retry client errors", method: http.MethodGet, statusCode: http.StatusNotFound},
+		{name: "Retries requests that could not connect", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "Does not retry requests that might have been sent", err: &net.OpError{Op: "read", Err: errors.New("connection reset")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response *http.Response
+			if tt.err == nil {
+				request, _ := http.NewRequest(tt.method, "https://gitlab.example.com/api/v4/projects/42/issues", nil)
+				response = &http.Response{StatusCode: tt.statusCode, Request: request}
+			}
+			got, _ := checkRetry(context.Background(), response, tt.err)
+			if got != tt.want {
+				t.Errorf("checkRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateIssueIsNotRetried(t *testing.T) {
+	issueRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		issueRequests++
+		writer.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	t.Setenv("CI_API_V4_URL", server.URL+"/api/v4")
+	t.Setenv("GITLAB_ISSUE_AUTOMATION_API_TOKEN", "token")
+	t.Setenv("GITLAB_RETRY_WAIT_MIN", "1ms")
+	t.Setenv("GITLAB_RETRY_WAIT_MAX", "5ms")
+	gitClient, gitProject, transport = nil, nil, nil
+	defer func() { gitClient, gitProject, transport = nil, nil, nil }()
+	git, err := GetGitClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = git.Issues.CreateIssue(42, &gitlab.CreateIssueOptions{Title: gitlab.Ptr("Daily chore")})
+	if err == nil || issueRequests != 1 || GetAPIStats().Retries != 0 {
+		t.Errorf("CreateIssue() = %v after %d requests and %d retries, want 1 request", err, issueRequests, GetAPIStats().Retries)
+	}
+	// The last failed attempt is not counted as a retry
+	_, _, err = git.Issues.GetIssue(42, 1)
+	if err == nil || issueRequests != 7 || GetAPIStats().Retries != 5 {
+		t.Errorf("GetIssue() = %v after %d requests and %d retries, want 5 retries", err, issueRequests, GetAPIStats().Retries)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestAPITransportWaitsForRateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	remaining := 1
+	apiTransport := newAPITransport(roundTripFunc(func(request *http.Request) (*http.Response, error) {
+		remaining--
+		response := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+		response.Header.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		response.Header.Set("RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		return response, nil
+	}))
+	waited := time.Duration(0)
+	apiTransport.sleep = func(duration time.Duration) { waited += duration }
+	request, _ := http.NewRequest(http.MethodGet, "https://gitlab.example.com/api/v4/projects/42", nil)
+
+	apiTransport.RoundTrip(request)
+	if waited != 0 {
+		t.Errorf("RoundTrip() waited %v before rate limit was reached", waited)
+	}
+	apiTransport.RoundTrip(request)
+	if waited < 59*time.Minute {
+		t.Errorf("RoundTrip() waited %v, want until rate limit reset", waited)
+	}
+	if stats := apiTransport.getStats(); stats.RateLimitWaits != 1 || stats.Requests != 2 {
+		t.Errorf("getStats() = %v, want 2 requests and 1 wait", stats)
+	}
+}
+
+func TestGetGitClientInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "Fails without token", env: map[string]string{"GITLAB_ISSUE_AUTOMATION_API_TOKEN": ""}, wantErr: "GITLAB_ISSUE_AUTOMATION_API_TOKEN"},
+		{name: "Fails for invalid retry max", env: map[string]string{"GITLAB_RETRY_MAX": "many"}, wantErr: "GITLAB_RETRY_MAX"},
+		{name: "Fails for invalid retry wait", env: map[string]string{"GITLAB_RETRY_WAIT_MIN": "5"}, wantErr: "GITLAB_RETRY_WAIT_MIN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITLAB_ISSUE_AUTOMATION_API_TOKEN", "token")
+			t.Setenv("CI_API_V4_URL", "https://gitlab.example.com/api/v4")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			gitClient, gitProject, transport = nil, nil, nil
+			defer func() { gitClient, gitProject, transport = nil, nil, nil }()
+			_, err := GetGitClient()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GetGitClient() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
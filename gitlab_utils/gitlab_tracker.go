@@ -0,0 +1,486 @@
+package gitlabUtils
+
+import (
+	"fmt"
+	types "gitlab-issue-automation/types"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+type gitlabTracker struct{}
+
+func getGitClientAndProject() (*gitlab.Client, *gitlab.Project, error) {
+	git, err := GetGitClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	project, err := GetGitProject()
+	if err != nil {
+		return nil, nil, err
+	}
+	return git, project, nil
+}
+
+func (t *gitlabTracker) ListIssues(orderBy string, sortOrder string, issueState string) ([]*gitlab.Issue, error) {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return nil, err
+	}
+	perPage := 20
+	page := 1
+	lastPageReached := false
+	var issues []*gitlab.Issue
+	for {
+		if lastPageReached {
+			break
+		}
+		listOptions := &gitlab.ListOptions{
+			PerPage: perPage,
+			Page:    page,
+		}
+		options := &gitlab.ListProjectIssuesOptions{
+			OrderBy:     &orderBy,
+			Sort:        &sortOrder,
+			ListOptions: *listOptions,
+		}
+		if issueState != "" {
+			options.State = &issueState
+		}
+		pageIssues, _, err := git.Issues.ListProjectIssues(project.ID, options)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, pageIssues...)
+		if len(pageIssues) < perPage {
+			lastPageReached = true
+		} else {
+			page++
+		}
+	}
+	return issues, nil
+}
+
+func (t *gitlabTracker) SearchIssues(search string) ([]*gitlab.Issue, error) {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return nil, err
+	}
+	// All pages are fetched since the search also matches loosely, so the
+	// wanted issues need not be on the first page
+	orderBy := "created_at"
+	perPage := 20
+	page := 1
+	lastPageReached := false
+	var issues []*gitlab.Issue
+	for {
+		if lastPageReached {
+			break
+		}
+		listOptions := &gitlab.ListOptions{
+			PerPage: perPage,
+			Page:    page,
+		}
+		options := &gitlab.ListProjectIssuesOptions{
+			Search:      &search,
+			OrderBy:     &orderBy,
+			ListOptions: *listOptions,
+		}
+		pageIssues, _, err := git.Issues.ListProjectIssues(project.ID, options)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, pageIssues...)
+		if len(pageIssues) < perPage {
+			lastPageReached = true
+		} else {
+			page++
+		}
+	}
+	return issues, nil
+}
+
+func (t *gitlabTracker) CreateIssue(options *gitlab.CreateIssueOptions) (*gitlab.Issue, error) {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return nil, err
+	}
+	issue, _, err := git.Issues.CreateIssue(project.ID, options)
+	return issue, err
+}
+
+func (t *gitlabTracker) UpdateIssue(issueId int, options *gitlab.UpdateIssueOptions) (*gitlab.Issue, error) {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return nil, err
+	}
+	issue, _, err := git.Issues.UpdateIssue(project.ID, issueId, options)
+	return issue, err
+}
+
+func (t *gitlabTracker) SetTimeEstimate(issueId int, duration string) error {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return err
+	}
+	_, _, err = git.Issues.SetTimeEstimate(project.ID, issueId, &gitlab.SetTimeEstimateOptions{Duration: &duration})
+	return err
+}
+
+// Links are created within the project
+func (t *gitlabTracker) CreateIssueLink(issueId int, targetIssueId int, linkType string) error {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return err
+	}
+	_, _, err = git.IssueLinks.CreateIssueLink(project.ID, issueId, &gitlab.CreateIssueLinkOptions{
+		TargetProjectID: gitlab.Ptr(strconv.Itoa(project.ID)),
+		TargetIssueIID:  gitlab.Ptr(strconv.Itoa(targetIssueId)),
+		LinkType:        &linkType,
+	})
+	return err
+}
+
+// Only links within the project are listed
+func (t *gitlabTracker) ListIssueLinks(issueId int) ([]int, error) {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return nil, err
+	}
+	relations, _, err := git.IssueLinks.ListIssueRelations(project.ID, issueId)
+	if err != nil {
+		return nil, err
+	}
+	linkedIds := []int{}
+	for _, relation := range relations {
+		if relation.ProjectID == project.ID {
+			linkedIds = append(linkedIds, relation.IID)
+		}
+	}
+	return linkedIds, nil
+}
+
+func (t *gitlabTracker) CreateIssueNote(issueId int, body string) error {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return err
+	}
+	_, _, err = git.Notes.CreateIssueNote(project.ID, issueId, &gitlab.CreateIssueNoteOptions{Body: &body})
+	return err
+}
+
+// Epics belong to the group of the project
+func (t *gitlabTracker) GetEpicId(epicIid int) (int, error) {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return 0, err
+	}
+	if project.Namespace == nil || project.Namespace.Kind != "group" {
+		return 0, fmt.Errorf("epics can only be used for projects in a group")
+	}
+	epic, response, err := git.Epics.GetEpic(project.Namespace.ID, epicIid)
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return epic.ID, nil
+}
+
+// Active milestones of the project and its groups
+func (t *gitlabTracker) ListMilestones() ([]*gitlab.Milestone, error) {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return nil, err
+	}
+	options := &gitlab.ListMilestonesOptions{
+		State:                   gitlab.Ptr("active"),
+		IncludeParentMilestones: gitlab.Ptr(true),
+		ListOptions:             gitlab.ListOptions{PerPage: 100, Page: 1},
+	}
+	var milestones []*gitlab.Milestone
+	for {
+		pageMilestones, response, err := git.Milestones.ListMilestones(project.ID, options)
+		if err != nil {
+			return nil, err
+		}
+		milestones = append(milestones, pageMilestones...)
+		if response.NextPage == 0 {
+			return milestones, nil
+		}
+		options.Page = response.NextPage
+	}
+}
+
+// Upcoming and current iterations of the project's groups
+func (t *gitlabTracker) ListIterations() ([]*gitlab.ProjectIteration, error) {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return nil, err
+	}
+	options := &gitlab.ListProjectIterationsOptions{
+		State:            gitlab.Ptr("opened"),
+		IncludeAncestors: gitlab.Ptr(true),
+		ListOptions:      gitlab.ListOptions{PerPage: 100, Page: 1},
+	}
+	var iterations []*gitlab.ProjectIteration
+	for {
+		pageIterations, response, err := git.ProjectIterations.ListProjectIterations(project.ID, options)
+		if err != nil {
+			return nil, err
+		}
+		iterations = append(iterations, pageIterations...)
+		if response.NextPage == 0 {
+			return iterations, nil
+		}
+		options.Page = response.NextPage
+	}
+}
+
+func (t *gitlabTracker) GetWikiPage(title string) (*types.WikiMetadata, error) {
+	git, err := GetGitClient()
+	if err != nil {
+		return nil, err
+	}
+	groupWikiId := GetGroupWikiId()
+	if groupWikiId == "" {
+		project, err := GetGitProject()
+		if err != nil {
+			return nil, err
+		}
+		wikiPage, _, err := git.Wikis.GetWikiPage(project.ID, title, &gitlab.GetWikiPageOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &types.WikiMetadata{Title: wikiPage.Title, Slug: wikiPage.Slug}, nil
+	}
+	wikiPage, _, err := git.GroupWikis.GetGroupWikiPage(groupWikiId, title, &gitlab.GetGroupWikiPageOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &types.WikiMetadata{Title: wikiPage.Title, Slug: wikiPage.Slug}, nil
+}
+
+func (t *gitlabTracker) ListWikiPages() ([]types.WikiMetadata, error) {
+	git, err := GetGitClient()
+	if err != nil {
+		return nil, err
+	}
+	groupWikiId := GetGroupWikiId()
+	var wikiMetadata []types.WikiMetadata
+	if groupWikiId == "" {
+		project, err := GetGitProject()
+		if err != nil {
+			return nil, err
+		}
+		options := &gitlab.ListWikisOptions{}
+		wikiPages, _, err := git.Wikis.ListWikis(project.ID, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, wikiPage := range wikiPages {
+			wikiMetadata = append(wikiMetadata, types.WikiMetadata{Title: wikiPage.Title, Slug: wikiPage.Slug})
+		}
+	} else {
+		options := &gitlab.ListGroupWikisOptions{}
+		wikiPages, _, err := git.GroupWikis.ListGroupWikis(groupWikiId, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, wikiPage := range wikiPages {
+			wikiMetadata = append(wikiMetadata, types.WikiMetadata{Title: wikiPage.Title, Slug: wikiPage.Slug})
+		}
+	}
+	return wikiMetadata, nil
+}
+
+func (t *gitlabTracker) CreateWikiPage(title string, content string) error {
+	git, err := GetGitClient()
+	if err != nil {
+		return err
+	}
+	groupWikiId := GetGroupWikiId()
+	format := gitlab.WikiFormatValue("markdown")
+	if groupWikiId == "" {
+		project, err := GetGitProject()
+		if err != nil {
+			return err
+		}
+		options := &gitlab.CreateWikiPageOptions{
+			Content: &content,
+			Title:   &title,
+			Format:  &format,
+		}
+		_, _, err = git.Wikis.CreateWikiPage(project.ID, options)
+		return err
+	}
+	options := &gitlab.CreateGroupWikiPageOptions{
+		Content: &content,
+		Title:   &title,
+		Format:  &format,
+	}
+	_, _, err = git.GroupWikis.CreateGroupWikiPage(groupWikiId, options)
+	return err
+}
+
+func (t *gitlabTracker) GetLastSuccessfulScheduledPipelineTime(scheduleId int) (time.Time, error) {
+	git, err := GetGitClient()
+	if err != nil {
+		return time.Unix(0, 0), err
+	}
+	lastSuccessfulPipeline := time.Unix(0, 0)
+	ciProjectID, err := GetCiProjectId()
+	if err != nil {
+		return lastSuccessfulPipeline, err
+	}
+
+	schedule, _, err := git.PipelineSchedules.GetPipelineSchedule(ciProjectID, scheduleId)
+	if err != nil {
+		return lastSuccessfulPipeline, err
+	}
+
+	// Find latest successful pipeline, with pagination.
+	// Need to loop through all pipelines, only since 17.2 sorting has been introduced https://gitlab.com/gitlab-org/gitlab/-/issues/37246.
+	// Cannot use schedule.LastPipeline as the status can be failed.
+	pipelinesTriggeredByScheduleOptions := gitlab.ListPipelinesTriggeredByScheduleOptions{
+		Page: 1, PerPage: 10, Sort: "desc",
+	}
+
+	for {
+		pipelines, pipelinesTriggeredByScheduleResponse, err := git.PipelineSchedules.ListPipelinesTriggeredBySchedule(ciProjectID, schedule.ID, &pipelinesTriggeredByScheduleOptions)
+		if err != nil {
+			return lastSuccessfulPipeline, err
+		}
+
+		for _, pipeline := range pipelines {
+			if pipeline.Status == "success" {
+				lastSuccessfulPipeline = *pipeline.CreatedAt
+			}
+		}
+
+		if pipelinesTriggeredByScheduleResponse.NextPage == 0 {
+			break
+		}
+		pipelinesTriggeredByScheduleOptions.Page = pipelinesTriggeredByScheduleResponse.NextPage
+	}
+
+	return lastSuccessfulPipeline, nil
+}
+
+func (t *gitlabTracker) GetUserId(username string) (int, error) {
+	git, err := GetGitClient()
+	if err != nil {
+		return 0, err
+	}
+	users, _, err := git.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.Ptr(username)})
+	if err != nil {
+		return 0, err
+	}
+	for _, user := range users {
+		if user.Username == username {
+			return user.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+func (t *gitlabTracker) GetProjectVariable(key string) (string, error) {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return "", err
+	}
+	variable, response, err := git.ProjectVariables.GetVariable(project.ID, key, nil)
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return variable.Value, nil
+}
+
+func (t *gitlabTracker) SetProjectVariable(key string, value string) error {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return err
+	}
+	_, response, err := git.ProjectVariables.UpdateVariable(project.ID, key, &gitlab.UpdateProjectVariableOptions{
+		Value: &value,
+		Raw:   gitlab.Ptr(true),
+	})
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		_, _, err = git.ProjectVariables.CreateVariable(project.ID, &gitlab.CreateProjectVariableOptions{
+			Key:   &key,
+			Value: &value,
+			Raw:   gitlab.Ptr(true),
+		})
+	}
+	return err
+}
+
+func (t *gitlabTracker) getSnippet(title string) (*gitlab.Snippet, error) {
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return nil, err
+	}
+	options := &gitlab.ListProjectSnippetsOptions{Page: 1, PerPage: 20}
+	for {
+		snippets, response, err := git.ProjectSnippets.ListSnippets(project.ID, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, snippet := range snippets {
+			if snippet.Title == title {
+				return snippet, nil
+			}
+		}
+		if response.NextPage == 0 {
+			return nil, nil
+		}
+		options.Page = response.NextPage
+	}
+}
+
+func (t *gitlabTracker) GetSnippetContent(title string) (string, error) {
+	snippet, err := t.getSnippet(title)
+	if err != nil || snippet == nil {
+		return "", err
+	}
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return "", err
+	}
+	content, _, err := git.ProjectSnippets.SnippetContent(project.ID, snippet.ID)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+func (t *gitlabTracker) SetSnippetContent(title string, content string) error {
+	snippet, err := t.getSnippet(title)
+	if err != nil {
+		return err
+	}
+	git, project, err := getGitClientAndProject()
+	if err != nil {
+		return err
+	}
+	if snippet == nil {
+		visibility := gitlab.PrivateVisibility
+		_, _, err = git.ProjectSnippets.CreateSnippet(project.ID, &gitlab.CreateProjectSnippetOptions{
+			Title:      &title,
+			FileName:   gitlab.Ptr(title + ".json"),
+			Content:    &content,
+			Visibility: &visibility,
+		})
+		return err
+	}
+	_, _, err = git.ProjectSnippets.UpdateSnippet(project.ID, snippet.ID, &gitlab.UpdateProjectSnippetOptions{
+		Content: &content,
+	})
+	return err
+}
@@ -0,0 +1,44 @@
+package gitlabUtils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestGitlabTrackerSearchIssues(t *testing.T) {
+	matchingIssues := 45
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/api/v4/projects/42" {
+			fmt.Fprint(writer, `{"id": 42}`)
+			return
+		}
+		page, _ := strconv.Atoi(request.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(request.URL.Query().Get("per_page"))
+		issues := []map[string]int{}
+		for iid := (page-1)*perPage + 1; iid <= matchingIssues && iid <= page*perPage; iid++ {
+			issues = append(issues, map[string]int{"id": iid, "iid": iid})
+		}
+		err := json.NewEncoder(writer).Encode(issues)
+		if err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("CI_API_V4_URL", server.URL+"/api/v4")
+	t.Setenv("CI_PROJECT_ID", "42")
+	t.Setenv("GITLAB_ISSUE_AUTOMATION_API_TOKEN", "token")
+	gitClient, gitProject, transport = nil, nil, nil
+	defer func() { gitClient, gitProject, transport = nil, nil, nil }()
+
+	issues, err := (&gitlabTracker{}).SearchIssues(`template="chore.md"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != matchingIssues || issues[matchingIssues-1].IID != matchingIssues {
+		t.Errorf("SearchIssues() returned %d issues, want all %d pages", len(issues), matchingIssues)
+	}
+}
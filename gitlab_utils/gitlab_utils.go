@@ -1,14 +1,18 @@
 package gitlabUtils
 
 import (
-	"crypto/tls"
+	"fmt"
+	businessDays "gitlab-issue-automation/business_days"
+	config "gitlab-issue-automation/config"
 	"gitlab-issue-automation/constants"
+	occurrenceMarkers "gitlab-issue-automation/occurrence_markers"
 	types "gitlab-issue-automation/types"
 	"log"
-	"net/http"
 	"os"
 	"path"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/xanzy/go-gitlab"
@@ -17,270 +21,502 @@ import (
 type envVariableParameters struct {
 	Name                  string
 	ErrorMessageOverwrite string
-	Optional              bool
 }
 
-func GetEnvVariable(parameters *envVariableParameters) string {
+// Returns an error if the variable is not set, since required variables are
+// only read once they are needed during a run
+func GetEnvVariable(parameters *envVariableParameters) (string, error) {
 	envVariable := os.Getenv(parameters.Name)
-	if envVariable == "" && !parameters.Optional {
+	if envVariable == "" {
 		errorMessage := "This tool must be ran as part of a GitLab pipeline."
 		if parameters.ErrorMessageOverwrite != "" {
 			errorMessage = parameters.ErrorMessageOverwrite
 		}
-		log.Fatalf("Environment variable '%s' not found. %s", parameters.Name, errorMessage)
+		return "", fmt.Errorf("environment variable '%s' not found. %s", parameters.Name, errorMessage)
 	}
-	return envVariable
+	return envVariable, nil
 }
 
-func GetGitlabAPIToken() string {
+func getOptionalEnvVariable(name string) string {
+	return os.Getenv(name)
+}
+
+func GetGitlabAPIToken() (string, error) {
 	return GetEnvVariable(&envVariableParameters{
 		Name:                  "GITLAB_ISSUE_AUTOMATION_API_TOKEN",
-		ErrorMessageOverwrite: "Ensure this is set under the project CI/CD settings.",
+		ErrorMessageOverwrite: "Ensure this is set under the project CI/CD settings or use the -token flag.",
 	})
 }
 
-func GetCiProjectId() string {
-	return GetEnvVariable(&envVariableParameters{Name: "CI_PROJECT_ID"})
+func GetCiProjectId() (string, error) {
+	return GetEnvVariable(&envVariableParameters{
+		Name:                  "CI_PROJECT_ID",
+		ErrorMessageOverwrite: "Run this tool as part of a GitLab pipeline or use the -project flag.",
+	})
 }
 
-func GetCiAPIV4URL() string {
-	return GetEnvVariable(&envVariableParameters{Name: "CI_API_V4_URL"})
+func GetCiAPIV4URL() (string, error) {
+	return GetEnvVariable(&envVariableParameters{
+		Name:                  "CI_API_V4_URL",
+		ErrorMessageOverwrite: "Run this tool as part of a GitLab pipeline or use the -api-url flag.",
+	})
 }
 
+// The commands set it to the working directory if it is not given
 func GetCiProjectDir() string {
-	return GetEnvVariable(&envVariableParameters{Name: "CI_PROJECT_DIR"})
+	return getOptionalEnvVariable("CI_PROJECT_DIR")
+}
+
+func GetConfigPath() string {
+	configPath := getOptionalEnvVariable("ISSUE_AUTOMATION_CONFIG")
+	if configPath == "" {
+		configPath = path.Join(GetCiProjectDir(), constants.ConfigPath)
+	}
+	return configPath
+}
+
+func GetTemplateDir() string {
+	return getOptionalEnvVariable("ISSUE_TEMPLATE_DIR")
 }
 
 func GetGroupWikiId() string {
-	return GetEnvVariable(&envVariableParameters{Name: "GROUP_WIKI_ID", Optional: true})
+	return getOptionalEnvVariable("GROUP_WIKI_ID")
 }
 
-func GetScheduledPipelineId() int {
-	pipelineId := GetEnvVariable(&envVariableParameters{
+func GetScheduledPipelineId() (int, error) {
+	pipelineId, err := GetEnvVariable(&envVariableParameters{
 		Name: "RECURRING_TASKS_SCHEDULED_PIPELINE_ID",
 	})
+	if err != nil {
+		return 0, err
+	}
 
 	atoi, err := strconv.Atoi(pipelineId)
 	if err != nil {
-		log.Fatal(err)
+		return 0, fmt.Errorf("invalid RECURRING_TASKS_SCHEDULED_PIPELINE_ID: %w", err)
+	}
+
+	return atoi, nil
+}
+
+func HasScheduledPipelineId() bool {
+	return getOptionalEnvVariable("RECURRING_TASKS_SCHEDULED_PIPELINE_ID") != ""
+}
+
+func GetStateStoreType() string {
+	return getOptionalEnvVariable("STATE_STORE")
+}
+
+func GetStateStoreFile() string {
+	stateFile := getOptionalEnvVariable("STATE_STORE_FILE")
+	if stateFile == "" {
+		stateFile = path.Join(GetCiProjectDir(), ".gitlab/issue-automation-state.json")
+	}
+	return stateFile
+}
+
+func GetStateStoreVariable() string {
+	stateVariable := getOptionalEnvVariable("STATE_STORE_VARIABLE")
+	if stateVariable == "" {
+		stateVariable = "ISSUE_AUTOMATION_STATE"
+	}
+	return stateVariable
+}
+
+func GetStateStoreSnippet() string {
+	stateSnippet := getOptionalEnvVariable("STATE_STORE_SNIPPET")
+	if stateSnippet == "" {
+		stateSnippet = "issue-automation-state"
 	}
+	return stateSnippet
+}
 
-	return atoi
+func GetCatchUp() string {
+	return getOptionalEnvVariable("CATCH_UP")
 }
 
 func GetForceStandupNotesForToday() bool {
-	variable := GetEnvVariable(&envVariableParameters{Name: "FORCE_STANDUP_NOTES_FOR_TODAY", Optional: true})
+	variable := getOptionalEnvVariable("FORCE_STANDUP_NOTES_FOR_TODAY")
 	return variable == "TRUE"
 }
 
-func GetGitClient() *gitlab.Client {
-	transCfg := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+func GetPlanMode() bool {
+	variable := getOptionalEnvVariable("PLAN_MODE")
+	return variable == "TRUE"
+}
+
+// The client and project are shared by all API calls of a run
+var gitClient *gitlab.Client
+var gitProject *gitlab.Project
+var transport *apiTransport
+
+func GetGitClient() (*gitlab.Client, error) {
+	if gitClient != nil {
+		return gitClient, nil
+	}
+	httpClient, err := GetHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	token, err := GetGitlabAPIToken()
+	if err != nil {
+		return nil, err
 	}
-	httpClient := &http.Client{
-		Transport: transCfg,
+	apiURL, err := GetCiAPIV4URL()
+	if err != nil {
+		return nil, err
+	}
+	retryMax, err := GetRetryMax()
+	if err != nil {
+		return nil, err
+	}
+	retryWaitMin, err := GetRetryWaitMin()
+	if err != nil {
+		return nil, err
 	}
-	git, err := gitlab.NewClient(GetGitlabAPIToken(), gitlab.WithBaseURL(GetCiAPIV4URL()), gitlab.WithHTTPClient(httpClient))
+	retryWaitMax, err := GetRetryWaitMax()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	return git
+	transport = newAPITransport(httpClient.Transport)
+	httpClient.Transport = transport
+	git, err := gitlab.NewClient(
+		token,
+		gitlab.WithBaseURL(apiURL),
+		gitlab.WithHTTPClient(httpClient),
+		gitlab.WithCustomRetryMax(retryMax),
+		gitlab.WithCustomRetryWaitMinMax(retryWaitMin, retryWaitMax),
+		gitlab.WithCustomBackoff(getRetryBackoff),
+		gitlab.WithCustomRetry(checkRetry),
+		gitlab.WithRequestLogHook(transport.countRetry),
+	)
+	if err != nil {
+		return nil, err
+	}
+	gitClient = git
+	return gitClient, nil
 }
 
-func GetGitProject() *gitlab.Project {
-	git := GetGitClient()
-	project, _, err := git.Projects.GetProject(GetCiProjectId(), nil)
+func GetGitProject() (*gitlab.Project, error) {
+	if gitProject != nil {
+		return gitProject, nil
+	}
+	git, err := GetGitClient()
+	if err != nil {
+		return nil, err
+	}
+	projectId, err := GetCiProjectId()
+	if err != nil {
+		return nil, err
+	}
+	project, _, err := git.Projects.GetProject(projectId, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	return project
+	gitProject = project
+	return gitProject, nil
 }
 
 func GetRecurringIssuesPath() string {
-	return path.Join(GetCiProjectDir(), constants.IssueTemplatePath)
+	templateDir := GetTemplateDir()
+	if templateDir != "" {
+		return templateDir
+	}
+	issueTemplatePath := config.Get().IssueTemplatePath
+	if path.IsAbs(issueTemplatePath) {
+		return issueTemplatePath
+	}
+	return path.Join(GetCiProjectDir(), issueTemplatePath)
 }
 
-func GetLastRunTime() time.Time {
-	git := GetGitClient()
-	ciProjectID := GetCiProjectId()
-	ciScheduledPipelineId := GetScheduledPipelineId()
-
-	schedule, _, err := git.PipelineSchedules.GetPipelineSchedule(ciProjectID, ciScheduledPipelineId)
+func GetLastRunTime() (time.Time, error) {
+	scheduledPipelineId, err := GetScheduledPipelineId()
 	if err != nil {
-		log.Fatal(err)
+		return time.Time{}, err
 	}
+	return GetTracker().GetLastSuccessfulScheduledPipelineTime(scheduledPipelineId)
+}
+
+func GetSortedProjectIssues(orderBy string, sortOrder string, issueState string) ([]*gitlab.Issue, error) {
+	return GetTracker().ListIssues(orderBy, sortOrder, issueState)
+}
 
-	// Find latest successful pipeline, with pagination.
-	// Need to loop through all pipelines, only since 17.2 sorting has been introduced https://gitlab.com/gitlab-org/gitlab/-/issues/37246.
-	// Cannot use schedule.LastPipeline as the status can be failed.
-	pipelinesTriggeredByScheduleOptions := gitlab.ListPipelinesTriggeredByScheduleOptions{
-		Page: 1, PerPage: 10, Sort: "desc",
+func SearchProjectIssues(search string) ([]*gitlab.Issue, error) {
+	return GetTracker().SearchIssues(search)
+}
+
+// User IDs are looked up once per run
+var userIds = map[string]int{}
+
+func GetUserIds(usernames []string) ([]int, error) {
+	ids := []int{}
+	for _, username := range usernames {
+		username = strings.TrimPrefix(username, "@")
+		id, cached := userIds[username]
+		if !cached {
+			var err error
+			id, err = GetTracker().GetUserId(username)
+			if err != nil {
+				return nil, fmt.Errorf("could not look up assignee '%s': %w", username, err)
+			}
+			if id == 0 {
+				return nil, fmt.Errorf("unknown assignee '%s', use GitLab usernames", username)
+			}
+			userIds[username] = id
+		}
+		ids = append(ids, id)
 	}
+	return ids, nil
+}
+
+// Business days like 3bd are counted in workdays of the calendar
+var businessDaysPattern = regexp.MustCompile(`^(\d+)bd$`)
 
-	lastSuccessfulPipeline := time.Unix(0, 0)
+func GetCalendar() (*businessDays.Calendar, error) {
+	return businessDays.Load(GetRecurringIssuesPath())
+}
 
-	for {
-		pipelines, pipelinesTriggeredByScheduleResponse, err := git.PipelineSchedules.ListPipelinesTriggeredBySchedule(ciProjectID, schedule.ID, &pipelinesTriggeredByScheduleOptions)
+// Due dates given in business days are always on a workday, other due dates
+// are shifted like the occurrence
+func GetIssueDueDate(data *types.Metadata) (time.Time, error) {
+	match := businessDaysPattern.FindStringSubmatch(data.DueIn)
+	if match != nil {
+		workdays, err := strconv.Atoi(match[1])
 		if err != nil {
-			log.Fatal(err)
+			return time.Time{}, fmt.Errorf("invalid duein '%s': %w", data.DueIn, err)
 		}
-
-		for _, pipeline := range pipelines {
-			if pipeline.Status == "success" {
-				lastSuccessfulPipeline = *pipeline.CreatedAt
-			}
+		calendar, err := GetCalendar()
+		if err != nil {
+			return time.Time{}, err
 		}
-
-		if pipelinesTriggeredByScheduleResponse.NextPage == 0 {
-			break
+		return calendar.AddWorkdays(data.NextTime, workdays), nil
+	}
+	duration, err := time.ParseDuration(data.DueIn)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid duein '%s': %w", data.DueIn, err)
+	}
+	dueDate := data.NextTime.Add(duration)
+	if data.Shift == constants.ShiftNext || data.Shift == constants.ShiftPrevious {
+		calendar, err := GetCalendar()
+		if err != nil {
+			return time.Time{}, err
 		}
-		pipelinesTriggeredByScheduleOptions.Page = pipelinesTriggeredByScheduleResponse.NextPage
+		dueDate = calendar.Shift(dueDate, data.Shift)
 	}
+	return dueDate, nil
+}
 
-	return lastSuccessfulPipeline
+func GetOccurrenceIssue(data *types.Metadata) (*gitlab.Issue, error) {
+	marker := occurrenceMarkers.GetMarker(data)
+	issues, err := SearchProjectIssues(marker)
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range issues {
+		if strings.Contains(issue.Description, marker) {
+			return issue, nil
+		}
+	}
+	return nil, nil
 }
 
-func GetSortedProjectIssues(orderBy string, sortOrder string, issueState string) []*gitlab.Issue {
-	git := GetGitClient()
-	project := GetGitProject()
-	perPage := 20
-	page := 1
-	lastPageReached := false
-	var issues []*gitlab.Issue
-	for {
-		if lastPageReached {
-			break
+func getPreviousOccurrence(data *types.Metadata, beforeTime time.Time) (*gitlab.Issue, time.Time, error) {
+	issues, err := SearchProjectIssues(occurrenceMarkers.GetTemplateSearch(data))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	reference := occurrenceMarkers.GetTemplateReference(data)
+	var previousIssue *gitlab.Issue
+	var previousOccurrence time.Time
+	for _, issue := range issues {
+		issueReference, occurrence, found := occurrenceMarkers.ParseMarker(issue.Description)
+		if !found || issueReference != reference || !occurrence.Before(beforeTime) {
+			continue
 		}
-		listOptions := &gitlab.ListOptions{
-			PerPage: perPage,
-			Page:    page,
+		if previousIssue == nil || occurrence.After(previousOccurrence) {
+			previousIssue = issue
+			previousOccurrence = occurrence
 		}
-		options := &gitlab.ListProjectIssuesOptions{
-			OrderBy:     &orderBy,
-			Sort:        &sortOrder,
-			ListOptions: *listOptions,
+	}
+	return previousIssue, previousOccurrence, nil
+}
+
+// Returns the issue of the latest occurrence of the template before the given
+// one, or nil if there is none
+func GetPreviousOccurrenceIssue(data *types.Metadata) (*gitlab.Issue, error) {
+	previousIssue, _, err := getPreviousOccurrence(data, data.NextTime)
+	return previousIssue, err
+}
+
+// Returns the time of the latest occurrence of the template before the given
+// time as recorded in its marker, or the zero time if there is none
+func GetPreviousOccurrenceTime(data *types.Metadata, beforeTime time.Time) (time.Time, error) {
+	_, previousOccurrence, err := getPreviousOccurrence(data, beforeTime)
+	return previousOccurrence, err
+}
+
+// Creates the link unless the issue existed before the run and is already
+// linked to the target, so the steps after creating an issue can be repeated.
+// Returns whether the link was created.
+func CreateMissingIssueLink(issueId int, targetIssueId int, linkType string, existed bool) (bool, error) {
+	if existed {
+		linkedIds, err := GetTracker().ListIssueLinks(issueId)
+		if err != nil {
+			return false, err
 		}
-		if issueState != "" {
-			options.State = &issueState
+		for _, linkedId := range linkedIds {
+			if linkedId == targetIssueId {
+				return false, nil
+			}
 		}
-		pageIssues, _, err := git.Issues.ListProjectIssues(project.ID, options)
+	}
+	return true, GetTracker().CreateIssueLink(issueId, targetIssueId, linkType)
+}
+
+// Relates the issue to the issue of the previous occurrence, optionally with a
+// note on the previous issue that points to the new one. Existing issues that
+// are linked already get no second note.
+func LinkPreviousOccurrence(issue *gitlab.Issue, previousIssue *gitlab.Issue, addNote bool, existed bool) error {
+	created, err := CreateMissingIssueLink(issue.IID, previousIssue.IID, constants.RelatesToLinkType, existed)
+	if err != nil {
+		return fmt.Errorf("could not link issue #%d to previous occurrence #%d: %w", issue.IID, previousIssue.IID, err)
+	}
+	if addNote && created {
+		err = GetTracker().CreateIssueNote(previousIssue.IID, fmt.Sprintf("Next occurrence: #%d", issue.IID))
 		if err != nil {
-			log.Fatal(err)
+			return fmt.Errorf("could not add note to previous occurrence #%d: %w", previousIssue.IID, err)
 		}
-		issues = append(issues, pageIssues...)
-		if len(pageIssues) < perPage {
-			lastPageReached = true
-		} else {
-			page++
+	}
+	return nil
+}
+
+// Creates the issue of the occurrence, or returns the issue that already
+// exists for it together with true. The estimate is set for existing issues
+// without one, since setting it can fail after the issue was created.
+func CreateIssue(data *types.Metadata) (*gitlab.Issue, bool, error) {
+	existingIssue, err := GetOccurrenceIssue(data)
+	if err != nil {
+		return nil, false, err
+	}
+	if existingIssue != nil {
+		log.Println("-- Skipping creation because issue", existingIssue.IID, "already exists for this occurrence")
+		if data.Estimate != "" && !hasEstimate(existingIssue) {
+			err = setEstimate(existingIssue, data)
 		}
+		return existingIssue, true, err
 	}
-	return issues
+	issue, err := createIssue(data)
+	return issue, false, err
 }
 
-func GetIssueDueDate(data *types.Metadata) time.Time {
-	duration, err := time.ParseDuration(data.DueIn)
+func hasEstimate(issue *gitlab.Issue) bool {
+	return issue.TimeStats != nil && (issue.TimeStats.TimeEstimate > 0 || issue.TimeStats.HumanTimeEstimate != "")
+}
+
+func setEstimate(issue *gitlab.Issue, data *types.Metadata) error {
+	// The estimate can only be set after the issue exists
+	err := GetTracker().SetTimeEstimate(issue.IID, data.Estimate)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("issue #%d was created, but setting its estimate failed: %w", issue.IID, err)
 	}
-	return data.NextTime.Add(duration)
+	return nil
 }
 
-func CreateIssue(data *types.Metadata) error {
-	git := GetGitClient()
-	project := GetGitProject()
+func createIssue(data *types.Metadata) (*gitlab.Issue, error) {
+	labelOptions := gitlab.LabelOptions(append(data.Labels, config.Get().Labels.Recurring))
 
-	labelOptions := gitlab.LabelOptions(append(data.Labels, "🔁 Recurring"))
+	description := occurrenceMarkers.AddMarker(data.Description, data)
+	if data.Iteration != "" {
+		iterationId, err := GetIterationId(data)
+		if err != nil {
+			return nil, err
+		}
+		description += "\n" + GetIterationQuickAction(iterationId)
+	}
 
 	options := &gitlab.CreateIssueOptions{
 		Title:        gitlab.Ptr(data.Title),
-		Description:  gitlab.Ptr(data.Description),
+		Description:  gitlab.Ptr(description),
 		Confidential: &data.Confidential,
 		CreatedAt:    &data.NextTime,
 		Labels:       &labelOptions,
 	}
 	if data.DueIn != "" {
-		dueDate := gitlab.ISOTime(GetIssueDueDate(data))
+		issueDueDate, err := GetIssueDueDate(data)
+		if err != nil {
+			return nil, err
+		}
+		dueDate := gitlab.ISOTime(issueDueDate)
 		options.DueDate = &dueDate
 	}
-	_, _, err := git.Issues.CreateIssue(project.ID, options)
-	if err != nil {
-		return err
+	if data.Milestone != "" {
+		milestoneId, err := GetMilestoneId(data)
+		if err != nil {
+			return nil, err
+		}
+		options.MilestoneID = &milestoneId
 	}
-	return nil
-}
-
-func UpdateIssue(issueId int, options *gitlab.UpdateIssueOptions) *gitlab.Issue {
-	git := GetGitClient()
-	project := GetGitProject()
-	updatedIssue, _, err := git.Issues.UpdateIssue(project.ID, issueId, options)
-	if err != nil {
-		log.Fatal(err)
+	if len(data.Assignees) > 0 {
+		assigneeIds, err := GetUserIds(data.Assignees)
+		if err != nil {
+			return nil, err
+		}
+		options.AssigneeIDs = &assigneeIds
 	}
-	return updatedIssue
-}
-
-func WikiPageExists(title string) bool {
-	git := GetGitClient()
-	groupWikiId := GetGroupWikiId()
-	var err error
-	if groupWikiId == "" {
-		project := GetGitProject()
-		_, _, err = git.Wikis.GetWikiPage(project.ID, title, &gitlab.GetWikiPageOptions{})
-	} else {
-		_, _, err = git.GroupWikis.GetGroupWikiPage(groupWikiId, title, &gitlab.GetGroupWikiPageOptions{})
+	if data.Weight != nil {
+		options.Weight = gitlab.Ptr(*data.Weight)
 	}
-	return err == nil
-}
-
-func GetWikiPagesMetadata() []types.WikiMetadata {
-	git := GetGitClient()
-	groupWikiId := GetGroupWikiId()
-	var wikiMetadata []types.WikiMetadata
-	if groupWikiId == "" {
-		project := GetGitProject()
-		options := &gitlab.ListWikisOptions{}
-		wikiPages, _, err := git.Wikis.ListWikis(project.ID, options)
+	if data.IssueType != "" {
+		err := ValidateIssueType(data.IssueType)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-		for _, wikiPage := range wikiPages {
-			wikiMetadata = append(wikiMetadata, types.WikiMetadata{Title: wikiPage.Title, Slug: wikiPage.Slug})
-		}
-	} else {
-		options := &gitlab.ListGroupWikisOptions{}
-		wikiPages, _, err := git.GroupWikis.ListGroupWikis(groupWikiId, options)
+		options.IssueType = gitlab.Ptr(data.IssueType)
+	}
+	if data.Epic != "" {
+		epicId, err := GetEpicId(data)
 		if err != nil {
-			log.Fatal(err)
-		}
-		for _, wikiPage := range wikiPages {
-			wikiMetadata = append(wikiMetadata, types.WikiMetadata{Title: wikiPage.Title, Slug: wikiPage.Slug})
+			return nil, err
 		}
+		options.EpicID = &epicId
 	}
-	return wikiMetadata
-}
-
-func CreateWikiPage(title string, content string) {
-	git := GetGitClient()
-	groupWikiId := GetGroupWikiId()
-	format := gitlab.WikiFormatValue("markdown")
-	var err error
-	if groupWikiId == "" {
-		project := GetGitProject()
-		options := &gitlab.CreateWikiPageOptions{
-			Content: &content,
-			Title:   &title,
-			Format:  &format,
-		}
-		_, _, err = git.Wikis.CreateWikiPage(project.ID, options)
-	} else {
-		options := &gitlab.CreateGroupWikiPageOptions{
-			Content: &content,
-			Title:   &title,
-			Format:  &format,
+	if data.Estimate != "" {
+		err := ValidateEstimate(data.Estimate)
+		if err != nil {
+			return nil, err
 		}
-		_, _, err = git.GroupWikis.CreateGroupWikiPage(groupWikiId, options)
 	}
+	issue, err := GetTracker().CreateIssue(options)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	if data.Estimate != "" {
+		return issue, setEstimate(issue, data)
+	}
+	return issue, nil
+}
+
+func UpdateIssue(issueId int, options *gitlab.UpdateIssueOptions) (*gitlab.Issue, error) {
+	return GetTracker().UpdateIssue(issueId, options)
+}
+
+func CreateIssueLink(issueId int, targetIssueId int, linkType string) error {
+	return GetTracker().CreateIssueLink(issueId, targetIssueId, linkType)
+}
+
+func CreateIssueNote(issueId int, body string) error {
+	return GetTracker().CreateIssueNote(issueId, body)
+}
+
+func WikiPageExists(title string) bool {
+	_, err := GetTracker().GetWikiPage(title)
+	return err == nil
+}
+
+func GetWikiPagesMetadata() ([]types.WikiMetadata, error) {
+	return GetTracker().ListWikiPages()
+}
+
+func CreateWikiPage(title string, content string) error {
+	return GetTracker().CreateWikiPage(title, content)
 }
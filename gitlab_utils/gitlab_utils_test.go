@@ -0,0 +1,41 @@
+package gitlabUtils
+
+import (
+	"reflect"
+	"testing"
+)
+
+type countingTracker struct {
+	*MemoryTracker
+	userLookups int
+}
+
+func (t *countingTracker) GetUserId(username string) (int, error) {
+	t.userLookups++
+	return t.MemoryTracker.GetUserId(username)
+}
+
+func TestGetUserIds(t *testing.T) {
+	memoryTracker := NewMemoryTracker()
+	memoryTracker.Users = map[string]int{"alice": 7, "bob": 8}
+	tracker := &countingTracker{MemoryTracker: memoryTracker}
+	SetTracker(tracker)
+	defer SetTracker(nil)
+
+	for i := 0; i < 2; i++ {
+		ids, err := GetUserIds([]string{"alice", "@bob"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids, []int{7, 8}) {
+			t.Errorf("GetUserIds() = %v, want [7 8]", ids)
+		}
+	}
+	if tracker.userLookups != 2 {
+		t.Errorf("GetUserIds() looked up users %d times, want 2", tracker.userLookups)
+	}
+	_, err := GetUserIds([]string{"mallory"})
+	if err == nil {
+		t.Error("GetUserIds() should fail for unknown users")
+	}
+}
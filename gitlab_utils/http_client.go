@@ -0,0 +1,98 @@
+package gitlabUtils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+func GetCaFile() string {
+	caFile := getOptionalEnvVariable("GITLAB_CA_FILE")
+	if caFile == "" {
+		// Set by GitLab runners if the instance uses a custom certificate
+		caFile = getOptionalEnvVariable("CI_SERVER_TLS_CA_FILE")
+	}
+	return caFile
+}
+
+func GetClientCertFile() string {
+	return getOptionalEnvVariable("GITLAB_CLIENT_CERT_FILE")
+}
+
+func GetClientKeyFile() string {
+	return getOptionalEnvVariable("GITLAB_CLIENT_KEY_FILE")
+}
+
+func GetInsecureSkipVerify() bool {
+	variable := getOptionalEnvVariable("GITLAB_INSECURE_SKIP_VERIFY")
+	return variable == "TRUE"
+}
+
+func GetProxy() string {
+	return getOptionalEnvVariable("GITLAB_PROXY")
+}
+
+func getTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if GetInsecureSkipVerify() {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	caFile := GetCaFile()
+	if caFile != "" {
+		caCertificates, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle: %w", err)
+		}
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(caCertificates) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", caFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	clientCertFile := GetClientCertFile()
+	clientKeyFile := GetClientKeyFile()
+	if (clientCertFile == "") != (clientKeyFile == "") {
+		return nil, fmt.Errorf("both GITLAB_CLIENT_CERT_FILE and GITLAB_CLIENT_KEY_FILE need to be set for client certificates")
+	}
+	if clientCertFile != "" {
+		clientCertificate, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCertificate}
+	}
+	return tlsConfig, nil
+}
+
+func getProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	proxy := GetProxy()
+	if proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse GITLAB_PROXY: %w", err)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+func GetHTTPClient() (*http.Client, error) {
+	tlsConfig, err := getTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxyFunc, err := getProxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxyFunc
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,85 @@
+package gitlabUtils
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caCertificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	err := os.WriteFile(caFile, caCertificate, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalidFile := filepath.Join(t.TempDir(), "invalid.pem")
+	err = os.WriteFile(invalidFile, []byte("invalid"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		env            map[string]string
+		wantClientErr  bool
+		wantRequestErr bool
+	}{
+		{
+			name:           "Verifies certificates by default",
+			wantRequestErr: true,
+		},
+		{
+			name: "Uses custom CA bundle",
+			env:  map[string]string{"GITLAB_CA_FILE": caFile},
+		},
+		{
+			name: "Uses CA bundle of GitLab runner",
+			env:  map[string]string{"CI_SERVER_TLS_CA_FILE": caFile},
+		},
+		{
+			name: "Skips verification on explicit opt-in",
+			env:  map[string]string{"GITLAB_INSECURE_SKIP_VERIFY": "TRUE"},
+		},
+		{
+			name:          "Fails for invalid CA bundle",
+			env:           map[string]string{"GITLAB_CA_FILE": invalidFile},
+			wantClientErr: true,
+		},
+		{
+			name:          "Fails for client certificate without key",
+			env:           map[string]string{"GITLAB_CLIENT_CERT_FILE": caFile},
+			wantClientErr: true,
+		},
+		{
+			name:          "Fails for invalid proxy",
+			env:           map[string]string{"GITLAB_PROXY": "://proxy"},
+			wantClientErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"GITLAB_CA_FILE", "CI_SERVER_TLS_CA_FILE", "GITLAB_INSECURE_SKIP_VERIFY", "GITLAB_CLIENT_CERT_FILE", "GITLAB_CLIENT_KEY_FILE", "GITLAB_PROXY"} {
+				t.Setenv(name, tt.env[name])
+			}
+			client, err := GetHTTPClient()
+			if (err != nil) != tt.wantClientErr {
+				t.Fatalf("GetHTTPClient() error = %v, wantErr %v", err, tt.wantClientErr)
+			}
+			if err != nil {
+				return
+			}
+			response, err := client.Get(server.URL)
+			if (err != nil) != tt.wantRequestErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantRequestErr)
+			}
+			if err == nil {
+				response.Body.Close()
+			}
+		})
+	}
+}
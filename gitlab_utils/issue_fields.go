@@ -0,0 +1,46 @@
+package gitlabUtils
+
+import (
+	"fmt"
+	"gitlab-issue-automation/constants"
+	types "gitlab-issue-automation/types"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Durations as accepted by GitLab time tracking, e.g. "1h 30m" or "1w2d"
+var estimatePattern = regexp.MustCompile(`^(\s*\d+(mo|w|d|h|m|s))+\s*$`)
+
+func ValidateIssueType(issueType string) error {
+	for _, knownType := range constants.IssueTypes {
+		if issueType == knownType {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown issueType '%s', use one of %s", issueType, strings.Join(constants.IssueTypes, ", "))
+}
+
+func ValidateEstimate(estimate string) error {
+	if !estimatePattern.MatchString(estimate) {
+		return fmt.Errorf("invalid estimate '%s', use a duration like 1h30m or 2d", estimate)
+	}
+	return nil
+}
+
+// Epics are referenced by their IID in the group of the project, with or
+// without the & prefix
+func GetEpicId(data *types.Metadata) (int, error) {
+	epicIid, err := strconv.Atoi(strings.TrimPrefix(data.Epic, "&"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid epic '%s', use the epic number like &12", data.Epic)
+	}
+	epicId, err := GetTracker().GetEpicId(epicIid)
+	if err != nil {
+		return 0, err
+	}
+	if epicId == 0 {
+		return 0, fmt.Errorf("unknown epic &%d", epicIid)
+	}
+	return epicId, nil
+}
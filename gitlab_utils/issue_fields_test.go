@@ -0,0 +1,73 @@
+package gitlabUtils
+
+import (
+	types "gitlab-issue-automation/types"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestValidateEstimate(t *testing.T) {
+	tests := []struct {
+		estimate string
+		wantErr  bool
+	}{
+		{estimate: "30m"},
+		{estimate: "1h30m"},
+		{estimate: "1w 2d"},
+		{estimate: "1mo"},
+		{estimate: "90", wantErr: true},
+		{estimate: "an hour", wantErr: true},
+		{estimate: "1.5h", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.estimate, func(t *testing.T) {
+			err := ValidateEstimate(tt.estimate)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEstimate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateIssueFields(t *testing.T) {
+	tracker := NewMemoryTracker()
+	tracker.Epics = []*gitlab.Epic{{ID: 105, IID: 5, Title: "Operations"}}
+	SetTracker(tracker)
+	defer SetTracker(nil)
+	data := &types.Metadata{
+		Title:     "Incident review",
+		Weight:    gitlab.Ptr(3),
+		Estimate:  "1h30m",
+		IssueType: "incident",
+		Epic:      "&5",
+		NextTime:  time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+	}
+
+	issue, _, err := CreateIssue(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := tracker.GetIssue(issue.IID)
+	if created.Weight != 3 || *created.IssueType != "incident" || created.Epic == nil || created.Epic.ID != 105 {
+		t.Errorf("CreateIssue() = %v, want weight, type and epic", created)
+	}
+	if created.TimeStats == nil || created.TimeStats.HumanTimeEstimate != "1h30m" {
+		t.Errorf("CreateIssue() time stats = %v, want estimate 1h30m", created.TimeStats)
+	}
+
+	for _, invalid := range []types.Metadata{
+		{Title: "Unknown type", IssueType: "bug"},
+		{Title: "Unknown epic", Epic: "&6"},
+		{Title: "Invalid estimate", Estimate: "soon"},
+	} {
+		_, _, err = CreateIssue(&invalid)
+		if err == nil {
+			t.Errorf("CreateIssue() should fail for %s", invalid.Title)
+		}
+	}
+	if len(tracker.Issues) != 1 {
+		t.Errorf("CreateIssue() created %d issues, want only the valid one", len(tracker.Issues))
+	}
+}
@@ -0,0 +1,345 @@
+package gitlabUtils
+
+import (
+	"fmt"
+	types "gitlab-issue-automation/types"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+type MemoryIssueLink struct {
+	IssueId       int
+	TargetIssueId int
+	LinkType      string
+}
+
+// MemoryTracker keeps issues, wiki pages and pipeline results in memory so
+// that the automation can be run without a GitLab instance, e.g. in tests.
+type MemoryTracker struct {
+	Issues             []*gitlab.Issue
+	IssueLinks         []MemoryIssueLink
+	Notes              map[int][]string
+	WikiPages          map[string]string
+	SuccessfulRunTimes map[int]time.Time
+	Variables          map[string]string
+	Snippets           map[string]string
+	Users              map[string]int
+	Milestones         []*gitlab.Milestone
+	Epics              []*gitlab.Epic
+	Iterations         []*gitlab.ProjectIteration
+	Now                func() time.Time
+}
+
+func NewMemoryTracker() *MemoryTracker {
+	return &MemoryTracker{
+		Notes:              map[int][]string{},
+		WikiPages:          map[string]string{},
+		SuccessfulRunTimes: map[int]time.Time{},
+		Variables:          map[string]string{},
+		Snippets:           map[string]string{},
+		Users:              map[string]int{},
+		Now:                time.Now,
+	}
+}
+
+func (t *MemoryTracker) AddIssue(issue *gitlab.Issue) *gitlab.Issue {
+	if issue.IID == 0 {
+		issue.IID = len(t.Issues) + 1
+	}
+	if issue.ID == 0 {
+		issue.ID = issue.IID
+	}
+	if issue.State == "" {
+		issue.State = "opened"
+	}
+	if issue.CreatedAt == nil {
+		createdAt := t.Now()
+		issue.CreatedAt = &createdAt
+	}
+	if issue.UpdatedAt == nil {
+		updatedAt := *issue.CreatedAt
+		issue.UpdatedAt = &updatedAt
+	}
+	if issue.WebURL == "" {
+		issue.WebURL = fmt.Sprintf("https://gitlab.example.com/issues/%d", issue.IID)
+	}
+	t.Issues = append(t.Issues, issue)
+	return issue
+}
+
+func (t *MemoryTracker) GetIssue(issueId int) *gitlab.Issue {
+	for _, issue := range t.Issues {
+		if issue.IID == issueId {
+			return issue
+		}
+	}
+	return nil
+}
+
+func (t *MemoryTracker) ListIssues(orderBy string, sortOrder string, issueState string) ([]*gitlab.Issue, error) {
+	issues := []*gitlab.Issue{}
+	for _, issue := range t.Issues {
+		if issueState == "" || issue.State == issueState {
+			issues = append(issues, copyIssue(issue))
+		}
+	}
+	sort.SliceStable(issues, func(firstIndex, secondIndex int) bool {
+		first, second := issueSortKey(issues[firstIndex], orderBy), issueSortKey(issues[secondIndex], orderBy)
+		if sortOrder == "asc" {
+			return first.Before(second)
+		}
+		return first.After(second)
+	})
+	return issues, nil
+}
+
+func (t *MemoryTracker) SearchIssues(search string) ([]*gitlab.Issue, error) {
+	issues, _ := t.ListIssues("created_at", "desc", "")
+	matchingIssues := []*gitlab.Issue{}
+	for _, issue := range issues {
+		if strings.Contains(issue.Title, search) || strings.Contains(issue.Description, search) {
+			matchingIssues = append(matchingIssues, issue)
+		}
+	}
+	return matchingIssues, nil
+}
+
+func (t *MemoryTracker) CreateIssue(options *gitlab.CreateIssueOptions) (*gitlab.Issue, error) {
+	issue := &gitlab.Issue{}
+	if options.Title == nil || *options.Title == "" {
+		return nil, fmt.Errorf("title is missing")
+	}
+	issue.Title = *options.Title
+	if options.Description != nil {
+		issue.Description = *options.Description
+	}
+	if options.Confidential != nil {
+		issue.Confidential = *options.Confidential
+	}
+	if options.Labels != nil {
+		issue.Labels = gitlab.Labels(*options.Labels)
+	}
+	if options.CreatedAt != nil {
+		createdAt := *options.CreatedAt
+		issue.CreatedAt = &createdAt
+	}
+	if options.DueDate != nil {
+		dueDate := *options.DueDate
+		issue.DueDate = &dueDate
+	}
+	if options.Weight != nil {
+		issue.Weight = *options.Weight
+	}
+	if options.IssueType != nil {
+		issueType := *options.IssueType
+		issue.IssueType = &issueType
+	}
+	if options.EpicID != nil {
+		for _, epic := range t.Epics {
+			if epic.ID == *options.EpicID {
+				issue.Epic = epic
+			}
+		}
+	}
+	if options.MilestoneID != nil {
+		for _, milestone := range t.Milestones {
+			if milestone.ID == *options.MilestoneID {
+				issue.Milestone = milestone
+			}
+		}
+	}
+	if options.AssigneeIDs != nil {
+		for _, assigneeId := range *options.AssigneeIDs {
+			assignee := &gitlab.IssueAssignee{ID: assigneeId}
+			for username, userId := range t.Users {
+				if userId == assigneeId {
+					assignee.Username = username
+				}
+			}
+			issue.Assignees = append(issue.Assignees, assignee)
+		}
+	}
+	return copyIssue(t.AddIssue(issue)), nil
+}
+
+func (t *MemoryTracker) UpdateIssue(issueId int, options *gitlab.UpdateIssueOptions) (*gitlab.Issue, error) {
+	issue := t.GetIssue(issueId)
+	if issue == nil {
+		return nil, fmt.Errorf("issue %d not found", issueId)
+	}
+	if options.Title != nil {
+		issue.Title = *options.Title
+	}
+	if options.Description != nil {
+		issue.Description = *options.Description
+	}
+	if options.Labels != nil {
+		issue.Labels = gitlab.Labels(*options.Labels)
+	}
+	if options.DueDate != nil {
+		dueDate := *options.DueDate
+		issue.DueDate = &dueDate
+	}
+	if options.StateEvent != nil {
+		switch *options.StateEvent {
+		case "close":
+			closedAt := t.Now()
+			issue.State = "closed"
+			issue.ClosedAt = &closedAt
+		case "reopen":
+			issue.State = "opened"
+			issue.ClosedAt = nil
+		}
+	}
+	updatedAt := t.Now()
+	issue.UpdatedAt = &updatedAt
+	return copyIssue(issue), nil
+}
+
+func (t *MemoryTracker) SetTimeEstimate(issueId int, duration string) error {
+	issue := t.GetIssue(issueId)
+	if issue == nil {
+		return fmt.Errorf("issue %d not found", issueId)
+	}
+	issue.TimeStats = &gitlab.TimeStats{HumanTimeEstimate: duration}
+	return nil
+}
+
+func (t *MemoryTracker) CreateIssueLink(issueId int, targetIssueId int, linkType string) error {
+	if t.GetIssue(issueId) == nil {
+		return fmt.Errorf("issue %d not found", issueId)
+	}
+	if t.GetIssue(targetIssueId) == nil {
+		return fmt.Errorf("issue %d not found", targetIssueId)
+	}
+	t.IssueLinks = append(t.IssueLinks, MemoryIssueLink{issueId, targetIssueId, linkType})
+	return nil
+}
+
+func (t *MemoryTracker) ListIssueLinks(issueId int) ([]int, error) {
+	linkedIds := []int{}
+	for _, link := range t.IssueLinks {
+		if link.IssueId == issueId {
+			linkedIds = append(linkedIds, link.TargetIssueId)
+		}
+		if link.TargetIssueId == issueId {
+			linkedIds = append(linkedIds, link.IssueId)
+		}
+	}
+	return linkedIds, nil
+}
+
+func (t *MemoryTracker) CreateIssueNote(issueId int, body string) error {
+	if t.GetIssue(issueId) == nil {
+		return fmt.Errorf("issue %d not found", issueId)
+	}
+	t.Notes[issueId] = append(t.Notes[issueId], body)
+	return nil
+}
+
+func (t *MemoryTracker) GetEpicId(epicIid int) (int, error) {
+	for _, epic := range t.Epics {
+		if epic.IID == epicIid {
+			return epic.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+func (t *MemoryTracker) ListMilestones() ([]*gitlab.Milestone, error) {
+	return t.Milestones, nil
+}
+
+func (t *MemoryTracker) ListIterations() ([]*gitlab.ProjectIteration, error) {
+	return t.Iterations, nil
+}
+
+func (t *MemoryTracker) GetWikiPage(title string) (*types.WikiMetadata, error) {
+	if _, exists := t.WikiPages[title]; !exists {
+		return nil, fmt.Errorf("wiki page %s not found", title)
+	}
+	return getMemoryWikiMetadata(title), nil
+}
+
+func (t *MemoryTracker) ListWikiPages() ([]types.WikiMetadata, error) {
+	var wikiMetadata []types.WikiMetadata
+	for title := range t.WikiPages {
+		wikiMetadata = append(wikiMetadata, *getMemoryWikiMetadata(title))
+	}
+	sort.Slice(wikiMetadata, func(firstIndex, secondIndex int) bool {
+		return wikiMetadata[firstIndex].Slug < wikiMetadata[secondIndex].Slug
+	})
+	return wikiMetadata, nil
+}
+
+func (t *MemoryTracker) CreateWikiPage(title string, content string) error {
+	if _, exists := t.WikiPages[title]; exists {
+		return fmt.Errorf("wiki page %s already exists", title)
+	}
+	t.WikiPages[title] = content
+	return nil
+}
+
+func (t *MemoryTracker) GetLastSuccessfulScheduledPipelineTime(scheduleId int) (time.Time, error) {
+	lastRunTime, exists := t.SuccessfulRunTimes[scheduleId]
+	if !exists {
+		return time.Unix(0, 0), nil
+	}
+	return lastRunTime, nil
+}
+
+func (t *MemoryTracker) GetUserId(username string) (int, error) {
+	return t.Users[username], nil
+}
+
+func (t *MemoryTracker) GetProjectVariable(key string) (string, error) {
+	return t.Variables[key], nil
+}
+
+func (t *MemoryTracker) SetProjectVariable(key string, value string) error {
+	t.Variables[key] = value
+	return nil
+}
+
+func (t *MemoryTracker) GetSnippetContent(title string) (string, error) {
+	return t.Snippets[title], nil
+}
+
+func (t *MemoryTracker) SetSnippetContent(title string, content string) error {
+	t.Snippets[title] = content
+	return nil
+}
+
+// Wiki titles are given as slugs, like GitLab the last segment is the title
+func getMemoryWikiMetadata(slug string) *types.WikiMetadata {
+	segments := strings.Split(slug, "/")
+	return &types.WikiMetadata{Title: segments[len(segments)-1], Slug: slug}
+}
+
+func issueSortKey(issue *gitlab.Issue, orderBy string) time.Time {
+	switch orderBy {
+	case "updated_at":
+		if issue.UpdatedAt != nil {
+			return *issue.UpdatedAt
+		}
+	case "due_date":
+		// Like GitLab, issues without due date are sorted last
+		if issue.DueDate != nil {
+			return time.Time(*issue.DueDate)
+		}
+		return time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if issue.CreatedAt != nil {
+		return *issue.CreatedAt
+	}
+	return time.Time{}
+}
+
+func copyIssue(issue *gitlab.Issue) *gitlab.Issue {
+	issueCopy := *issue
+	issueCopy.Labels = append(gitlab.Labels{}, issue.Labels...)
+	return &issueCopy
+}
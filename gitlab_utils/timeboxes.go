@@ -0,0 +1,119 @@
+package gitlabUtils
+
+import (
+	"fmt"
+	"gitlab-issue-automation/constants"
+	dateUtils "gitlab-issue-automation/date_utils"
+	types "gitlab-issue-automation/types"
+	"sort"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const milestoneKind = "milestone"
+const iterationKind = "iteration"
+
+// Milestones and iterations are both periods with optional start and due dates
+type timebox struct {
+	id        int
+	title     string
+	startDate string
+	dueDate   string
+}
+
+// Milestones and iterations are listed once per run
+var timeboxes = map[string][]timebox{}
+
+func getISODate(date *gitlab.ISOTime) string {
+	if date == nil {
+		return ""
+	}
+	return date.String()
+}
+
+func listTimeboxes(kind string) ([]timebox, error) {
+	if cached, exists := timeboxes[kind]; exists {
+		return cached, nil
+	}
+	kindTimeboxes := []timebox{}
+	switch kind {
+	case milestoneKind:
+		milestones, err := GetTracker().ListMilestones()
+		if err != nil {
+			return nil, err
+		}
+		for _, milestone := range milestones {
+			kindTimeboxes = append(kindTimeboxes, timebox{milestone.ID, milestone.Title, getISODate(milestone.StartDate), getISODate(milestone.DueDate)})
+		}
+	case iterationKind:
+		iterations, err := GetTracker().ListIterations()
+		if err != nil {
+			return nil, err
+		}
+		for _, iteration := range iterations {
+			kindTimeboxes = append(kindTimeboxes, timebox{iteration.ID, iteration.Title, getISODate(iteration.StartDate), getISODate(iteration.DueDate)})
+		}
+	}
+	sort.SliceStable(kindTimeboxes, func(i, j int) bool {
+		return kindTimeboxes[i].startDate < kindTimeboxes[j].startDate
+	})
+	timeboxes[kind] = kindTimeboxes
+	return kindTimeboxes, nil
+}
+
+// Resolves a title or the current or next timebox relative to the occurrence
+// date, dates are compared as ISO strings
+func resolveTimebox(kind string, selector string, kindTimeboxes []timebox, occurrenceTime time.Time) (timebox, error) {
+	occurrenceDate := occurrenceTime.Format(dateUtils.ShortISODateLayout)
+	for _, candidate := range kindTimeboxes {
+		switch selector {
+		case constants.CurrentTimeboxSelector:
+			if candidate.startDate != "" && candidate.dueDate != "" &&
+				candidate.startDate <= occurrenceDate && occurrenceDate <= candidate.dueDate {
+				return candidate, nil
+			}
+		case constants.NextTimeboxSelector:
+			if candidate.startDate > occurrenceDate {
+				return candidate, nil
+			}
+		default:
+			if candidate.title == selector {
+				return candidate, nil
+			}
+		}
+	}
+	switch selector {
+	case constants.CurrentTimeboxSelector:
+		return timebox{}, fmt.Errorf("no active %s contains %s", kind, occurrenceDate)
+	case constants.NextTimeboxSelector:
+		return timebox{}, fmt.Errorf("no active %s starts after %s", kind, occurrenceDate)
+	}
+	return timebox{}, fmt.Errorf("no active %s titled '%s'", kind, selector)
+}
+
+func getTimeboxId(kind string, selector string, data *types.Metadata) (int, error) {
+	kindTimeboxes, err := listTimeboxes(kind)
+	if err != nil {
+		return 0, err
+	}
+	resolved, err := resolveTimebox(kind, selector, kindTimeboxes, data.NextTime)
+	if err != nil {
+		return 0, err
+	}
+	return resolved.id, nil
+}
+
+func GetMilestoneId(data *types.Metadata) (int, error) {
+	return getTimeboxId(milestoneKind, data.Milestone, data)
+}
+
+func GetIterationId(data *types.Metadata) (int, error) {
+	return getTimeboxId(iterationKind, data.Iteration, data)
+}
+
+// Issues cannot be added to iterations when they are created through the
+// REST API, so a quick action is added to the description instead
+func GetIterationQuickAction(iterationId int) string {
+	return fmt.Sprintf("/iteration *iteration:%d", iterationId)
+}
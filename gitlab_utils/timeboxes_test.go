@@ -0,0 +1,87 @@
+package gitlabUtils
+
+import (
+	types "gitlab-issue-automation/types"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func getISOTime(date string) *gitlab.ISOTime {
+	parsed, _ := time.Parse("2006-01-02", date)
+	isoTime := gitlab.ISOTime(parsed)
+	return &isoTime
+}
+
+func TestResolveTimebox(t *testing.T) {
+	kindTimeboxes := []timebox{
+		{id: 1, title: "Backlog"},
+		{id: 2, title: "Sprint 1", startDate: "2024-03-04", dueDate: "2024-03-15"},
+		{id: 3, title: "Sprint 2", startDate: "2024-03-18", dueDate: "2024-03-29"},
+	}
+	tests := []struct {
+		name           string
+		selector       string
+		occurrenceTime time.Time
+		wantId         int
+		wantErr        bool
+	}{
+		{name: "Resolves title", selector: "Backlog", occurrenceTime: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC), wantId: 1},
+		{name: "Resolves current on start date", selector: "current", occurrenceTime: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC), wantId: 2},
+		{name: "Resolves current on due date", selector: "current", occurrenceTime: time.Date(2024, 3, 15, 23, 0, 0, 0, time.UTC), wantId: 2},
+		{name: "Resolves next", selector: "next", occurrenceTime: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC), wantId: 3},
+		{name: "Fails without current", selector: "current", occurrenceTime: time.Date(2024, 3, 16, 9, 0, 0, 0, time.UTC), wantErr: true},
+		{name: "Fails without next", selector: "next", occurrenceTime: time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC), wantErr: true},
+		{name: "Fails for unknown title", selector: "Sprint 3", occurrenceTime: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveTimebox(milestoneKind, tt.selector, kindTimeboxes, tt.occurrenceTime)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveTimebox() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.id != tt.wantId {
+				t.Errorf("resolveTimebox() = %v, want id %d", got, tt.wantId)
+			}
+		})
+	}
+}
+
+func TestCreateIssueTimeboxes(t *testing.T) {
+	tracker := NewMemoryTracker()
+	tracker.Milestones = []*gitlab.Milestone{
+		{ID: 11, Title: "March", StartDate: getISOTime("2024-03-01"), DueDate: getISOTime("2024-03-31")},
+		{ID: 12, Title: "April", StartDate: getISOTime("2024-04-01"), DueDate: getISOTime("2024-04-30")},
+	}
+	tracker.Iterations = []*gitlab.ProjectIteration{
+		{ID: 21, Title: "Sprint 1", StartDate: getISOTime("2024-03-04"), DueDate: getISOTime("2024-03-15")},
+	}
+	SetTracker(tracker)
+	defer SetTracker(nil)
+	data := &types.Metadata{
+		Title:     "Planning",
+		Milestone: "next",
+		Iteration: "current",
+		NextTime:  time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+	}
+
+	issue, _, err := CreateIssue(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Milestone == nil || issue.Milestone.ID != 12 {
+		t.Errorf("CreateIssue() milestone = %v, want April", issue.Milestone)
+	}
+	if !strings.HasSuffix(issue.Description, "\n/iteration *iteration:21") {
+		t.Errorf("CreateIssue() description = %v, want iteration quick action", issue.Description)
+	}
+
+	data.Milestone = "May"
+	data.NextTime = data.NextTime.AddDate(0, 0, 1)
+	_, _, err = CreateIssue(data)
+	if err == nil || !strings.Contains(err.Error(), "no active milestone titled 'May'") {
+		t.Errorf("CreateIssue() error = %v, want unknown milestone", err)
+	}
+}
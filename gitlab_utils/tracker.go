@@ -0,0 +1,56 @@
+package gitlabUtils
+
+import (
+	types "gitlab-issue-automation/types"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// Tracker is the backend all packages use to read and change issues, issue
+// links and notes, wikis, milestones, iterations, epics, pipeline schedules,
+// users, and the stored run state (project variables and snippets).
+// The GitLab API is used unless SetTracker is called.
+type Tracker interface {
+	ListIssues(orderBy string, sortOrder string, issueState string) ([]*gitlab.Issue, error)
+	SearchIssues(search string) ([]*gitlab.Issue, error)
+	CreateIssue(options *gitlab.CreateIssueOptions) (*gitlab.Issue, error)
+	UpdateIssue(issueId int, options *gitlab.UpdateIssueOptions) (*gitlab.Issue, error)
+	SetTimeEstimate(issueId int, duration string) error
+	CreateIssueLink(issueId int, targetIssueId int, linkType string) error
+	// Returns the IIDs of the issues linked to the issue
+	ListIssueLinks(issueId int) ([]int, error)
+	CreateIssueNote(issueId int, body string) error
+	// Returns 0 for unknown epics
+	GetEpicId(epicIid int) (int, error)
+	ListMilestones() ([]*gitlab.Milestone, error)
+	ListIterations() ([]*gitlab.ProjectIteration, error)
+	GetWikiPage(title string) (*types.WikiMetadata, error)
+	ListWikiPages() ([]types.WikiMetadata, error)
+	CreateWikiPage(title string, content string) error
+	// Returns the Unix epoch if no scheduled pipeline succeeded yet
+	GetLastSuccessfulScheduledPipelineTime(scheduleId int) (time.Time, error)
+	// Returns 0 for unknown users
+	GetUserId(username string) (int, error)
+	// Returns an empty string for missing variables
+	GetProjectVariable(key string) (string, error)
+	SetProjectVariable(key string, value string) error
+	// Returns an empty string for missing snippets
+	GetSnippetContent(title string) (string, error)
+	SetSnippetContent(title string, content string) error
+}
+
+var tracker Tracker
+
+func SetTracker(newTracker Tracker) {
+	tracker = newTracker
+	userIds = map[string]int{}
+	timeboxes = map[string][]timebox{}
+}
+
+func GetTracker() Tracker {
+	if tracker == nil {
+		tracker = &gitlabTracker{}
+	}
+	return tracker
+}
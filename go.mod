@@ -5,6 +5,7 @@ go 1.18
 require (
 	github.com/ericaro/frontmatter v0.0.0-20200210094738-46863cd917e2
 	github.com/gorhill/cronexpr v0.0.0-20180427100037-88b0669f7d75
+	github.com/hashicorp/go-retryablehttp v0.7.2
 	github.com/xanzy/go-gitlab v0.103.0
 	gopkg.in/yaml.v2 v2.3.0
 )
@@ -13,7 +14,6 @@ require (
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
-	github.com/hashicorp/go-retryablehttp v0.7.2 // indirect
 	golang.org/x/net v0.8.0 // indirect
 	golang.org/x/oauth2 v0.6.0 // indirect
 	golang.org/x/time v0.3.0 // indirect
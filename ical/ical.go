@@ -0,0 +1,196 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	dateUtils "gitlab-issue-automation/date_utils"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errUnsupportedRule = errors.New("unsupported RRULE")
+
+// Event is a VEVENT of an iCalendar file with the first and last day it
+// covers, yearly events recur on the same days every year
+type Event struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+	Yearly  bool
+}
+
+// A content line like DTSTART;TZID=Europe/Berlin:20260105T090000
+type property struct {
+	name       string
+	parameters map[string]string
+	value      string
+}
+
+// Parses the events of an iCalendar file as exported by calendar apps.
+// Times without Z or TZID are in the given location, and days are counted in
+// it. Recurring events are only supported with yearly rules without an end,
+// other recurring events are skipped with a warning.
+func Parse(contents string, location *time.Location) ([]Event, error) {
+	events := []Event{}
+	var properties []property
+	// Components nested in events, like alarms, are skipped
+	nestedDepth := 0
+	for _, line := range unfold(contents) {
+		if line == "" {
+			continue
+		}
+		current, err := parseProperty(line)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case current.name == "BEGIN" && current.value == "VEVENT":
+			properties = []property{}
+		case properties == nil:
+			continue
+		case current.name == "BEGIN":
+			nestedDepth++
+		case current.name == "END" && nestedDepth > 0:
+			nestedDepth--
+		case current.name == "END" && current.value == "VEVENT":
+			event, err := parseEvent(properties, location)
+			properties = nil
+			if errors.Is(err, errUnsupportedRule) {
+				log.Println("- Skipping", err)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+		case nestedDepth == 0:
+			properties = append(properties, current)
+		}
+	}
+	return events, nil
+}
+
+// Long lines are folded into lines starting with a space or tab
+func unfold(contents string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(contents, "\r\n", "\n"), "\n") {
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	return lines
+}
+
+func parseProperty(line string) (property, error) {
+	nameAndParameters, value, found := strings.Cut(line, ":")
+	if !found {
+		return property{}, fmt.Errorf("invalid line '%s'", line)
+	}
+	parts := strings.Split(nameAndParameters, ";")
+	parsed := property{name: strings.ToUpper(parts[0]), parameters: map[string]string{}, value: value}
+	for _, parameter := range parts[1:] {
+		name, parameterValue, _ := strings.Cut(parameter, "=")
+		parsed.parameters[strings.ToUpper(name)] = strings.Trim(parameterValue, `"`)
+	}
+	return parsed, nil
+}
+
+func parseEvent(properties []property, location *time.Location) (Event, error) {
+	event := Event{}
+	var end time.Time
+	endIsDate := false
+	rule := ""
+	for _, current := range properties {
+		var err error
+		switch current.name {
+		case "SUMMARY":
+			event.Summary = unescape(current.value)
+		case "DTSTART":
+			event.Start, _, err = parseTime(current, location)
+		case "DTEND":
+			end, endIsDate, err = parseTime(current, location)
+		case "RRULE":
+			rule = current.value
+			event.Yearly = true
+		}
+		if err != nil {
+			return event, fmt.Errorf("event '%s': %w", event.Summary, err)
+		}
+	}
+	if event.Start.IsZero() {
+		return event, fmt.Errorf("event '%s' has no DTSTART", event.Summary)
+	}
+	if event.Yearly {
+		err := validateYearlyRule(rule, event.Start)
+		if err != nil {
+			return event, fmt.Errorf("event '%s': %w", event.Summary, err)
+		}
+	}
+	event.Start = getDay(event.Start)
+	event.End = event.Start
+	if !end.IsZero() {
+		// The end is exclusive, so events end on the day before a date or
+		// before midnight
+		lastDay := getDay(end)
+		if endIsDate || end.Equal(lastDay) {
+			lastDay = lastDay.AddDate(0, 0, -1)
+		}
+		if lastDay.After(event.Start) {
+			event.End = lastDay
+		}
+	}
+	return event, nil
+}
+
+// Parses a date or time into the given location, dates are marked
+func parseTime(current property, location *time.Location) (time.Time, bool, error) {
+	timeLocation := location
+	if timezone, exists := current.parameters["TZID"]; exists {
+		var err error
+		timeLocation, err = time.LoadLocation(timezone)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown timezone '%s'", timezone)
+		}
+	}
+	parsed, err := dateUtils.ParseICalendarTime(current.value, timeLocation)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid %s: %w", current.name, err)
+	}
+	return parsed.In(location), len(current.value) == len(dateUtils.ICalendarDateLayout), nil
+}
+
+func getDay(value time.Time) time.Time {
+	return time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, value.Location())
+}
+
+// Yearly events on the day of DTSTART are exported as FREQ=YEARLY, sometimes
+// with BYMONTH and BYMONTHDAY of that day
+func validateYearlyRule(rule string, start time.Time) error {
+	unsupported := fmt.Errorf("%w '%s', only yearly events without an end on the day of DTSTART are supported", errUnsupportedRule, rule)
+	yearly := false
+	for _, part := range strings.Split(rule, ";") {
+		name, value, _ := strings.Cut(part, "=")
+		switch {
+		case name == "FREQ" && value == "YEARLY":
+			yearly = true
+		case name == "INTERVAL" && value == "1":
+		case name == "BYMONTH" && value == strconv.Itoa(int(start.Month())):
+		case name == "BYMONTHDAY" && value == strconv.Itoa(start.Day()):
+		case name == "WKST":
+		default:
+			return unsupported
+		}
+	}
+	if !yearly {
+		return unsupported
+	}
+	return nil
+}
+
+func unescape(text string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(text)
+}
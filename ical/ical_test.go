@@ -0,0 +1,95 @@
+package ical
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const calendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Summer vacation\\, Spain\r\n" +
+	"DTSTART;VALUE=DATE:20260803\r\n" +
+	"DTEND;VALUE=DATE:20260815\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Christmas br\r\n" +
+	" eak\r\n" +
+	"DTSTART;VALUE=DATE:20251224\r\n" +
+	"DTEND;VALUE=DATE:20260102\r\n" +
+	"RRULE:FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=24\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Offsite\r\n" +
+	"DTSTART;TZID=America/New_York:20260310T200000\r\n" +
+	"DTEND;TZID=America/New_York:20260311T120000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Dentist\r\n" +
+	"DTSTART:20260420T220000Z\r\n" +
+	"DTEND:20260420T230000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := Parse(calendar, location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, event := range events {
+		got = append(got, event.Summary+" "+event.Start.Format("2006-01-02")+" "+event.End.Format("2006-01-02")+" "+map[bool]string{true: "yearly", false: "once"}[event.Yearly])
+	}
+	want := []string{
+		// Dates end before DTEND
+		"Summer vacation, Spain 2026-08-03 2026-08-14 once",
+		"Christmas break 2025-12-24 2026-01-01 yearly",
+		// Times are converted to the days of the location
+		"Offsite 2026-03-11 2026-03-11 once",
+		"Dentist 2026-04-21 2026-04-21 once",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{name: "Rejects events without start", contents: "BEGIN:VEVENT\nSUMMARY:Vacation\nEND:VEVENT\n"},
+		{name: "Rejects invalid dates", contents: "BEGIN:VEVENT\nDTSTART:2026-08-03\nEND:VEVENT\n"},
+		{name: "Rejects unknown timezones", contents: "BEGIN:VEVENT\nDTSTART;TZID=Mars/Olympus:20260803T090000\nEND:VEVENT\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.contents, time.UTC)
+			if err == nil {
+				t.Error("Parse() did not fail")
+			}
+		})
+	}
+}
+
+func TestParseSkipsUnsupportedRules(t *testing.T) {
+	contents := "BEGIN:VEVENT\nSUMMARY:Standup\nDTSTART:20260803T090000\nRRULE:FREQ=WEEKLY;BYDAY=MO\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nSUMMARY:Workshop\nDTSTART:20260803\nRRULE:FREQ=YEARLY;COUNT=3\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nSUMMARY:Anniversary\nDTSTART:20260803\nRRULE:FREQ=YEARLY;BYMONTH=9;BYMONTHDAY=1\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nSUMMARY:Birthday\nDTSTART:20260803\nRRULE:FREQ=YEARLY;BYMONTH=8;BYMONTHDAY=3\nEND:VEVENT\n"
+	events, err := Parse(contents, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Summary != "Birthday" || !events[0].Yearly {
+		t.Errorf("Parse() = %v, want only yearly Birthday", events)
+	}
+}
@@ -1,24 +1,123 @@
-package main
-
-import (
-	boardLabels "gitlab-issue-automation/board_labels"
-	gitlabUtils "gitlab-issue-automation/gitlab_utils"
-	recurringIssues "gitlab-issue-automation/recurring_issues"
-	standupNotes "gitlab-issue-automation/standup_notes"
-	"log"
-	"time"
-)
-
-func main() {
-	lastRunTime := gitlabUtils.GetLastRunTime()
-	forceStandupNotesForToday := gitlabUtils.GetForceStandupNotesForToday()
-	log.Println("Last run:", lastRunTime.Format(time.RFC3339))
-	log.Println("Checking whether to create recurring issues")
-	recurringIssues.ProcessIssueFiles(lastRunTime)
-	log.Println("Checking whether to adapt board labels")
-	boardLabels.AdaptLabels()
-	boardLabels.CleanLabels(lastRunTime)
-	log.Println("Checking whether to create standup notes")
-	standupNotes.WriteNotes(lastRunTime, forceStandupNotesForToday)
-	log.Println("Run complete")
-}
+package main
+
+import (
+	"flag"
+	"fmt"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	"log"
+	"os"
+	"strings"
+	_ "time/tzdata" // Timezones are embedded since the Docker image has none
+)
+
+const usage = `Usage: gitlab-issue-automation [command] [flags]
+
+Commands:
+  run       Create due recurring issues, adapt board labels, and write standup notes (default)
+  plan      Print the changes of run without applying them
+  validate  Check templates and exceptions without calling the API
+  next      Print the next occurrences of all templates
+  standup   Write standup notes if they are due
+  labels    Adapt and clean board labels
+
+Run 'gitlab-issue-automation <command> -h' to see the flags of a command.
+Flags that are not given fall back to the environment variables of GitLab CI.
+`
+
+type command struct {
+	run func(flags *flag.FlagSet, args []string) []error
+	// Commands that do not call the API do not log API statistics
+	offline bool
+}
+
+var commands = map[string]command{
+	"run":      {run: runCommand},
+	"plan":     {run: planCommand},
+	"validate": {run: validateCommand, offline: true},
+	"next":     {run: nextCommand},
+	"standup":  {run: standupCommand},
+	"labels":   {run: labelsCommand},
+}
+
+// Without a command, all stages are run as before there were commands
+func parseCommand(args []string) (string, []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "run", args
+	}
+	return args[0], args[1:]
+}
+
+type commonFlags struct {
+	project     *string
+	apiURL      *string
+	token       *string
+	projectDir  *string
+	templateDir *string
+	config      *string
+}
+
+func addCommonFlags(flags *flag.FlagSet) *commonFlags {
+	return &commonFlags{
+		project:     flags.String("project", "", "ID or path of the project (default $CI_PROJECT_ID)"),
+		apiURL:      flags.String("api-url", "", "URL of the GitLab API, e.g. https://gitlab.com/api/v4 (default $CI_API_V4_URL)"),
+		token:       flags.String("token", "", "API access token (default $GITLAB_ISSUE_AUTOMATION_API_TOKEN)"),
+		projectDir:  flags.String("project-dir", "", "Checkout of the project (default $CI_PROJECT_DIR or the current directory)"),
+		templateDir: flags.String("template-dir", "", "Directory of the issue templates (default $ISSUE_TEMPLATE_DIR or issueTemplatePath of the config)"),
+		config:      flags.String("config", "", "Project config file (default $ISSUE_AUTOMATION_CONFIG or <project-dir>/.gitlab/issue-automation.yml)"),
+	}
+}
+
+// Given flags take precedence over the environment, which is read by all stages
+func (c *commonFlags) apply() error {
+	values := map[string]string{
+		"CI_PROJECT_ID":                     *c.project,
+		"CI_API_V4_URL":                     *c.apiURL,
+		"GITLAB_ISSUE_AUTOMATION_API_TOKEN": *c.token,
+		"CI_PROJECT_DIR":                    *c.projectDir,
+		"ISSUE_TEMPLATE_DIR":                *c.templateDir,
+		"ISSUE_AUTOMATION_CONFIG":           *c.config,
+	}
+	for name, value := range values {
+		if value == "" {
+			continue
+		}
+		err := os.Setenv(name, value)
+		if err != nil {
+			return err
+		}
+	}
+	if os.Getenv("CI_PROJECT_DIR") == "" {
+		workingDir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		return os.Setenv("CI_PROJECT_DIR", workingDir)
+	}
+	return nil
+}
+
+func main() {
+	name, args := parseCommand(os.Args[1:])
+	if name == "help" {
+		fmt.Fprint(os.Stdout, usage)
+		return
+	}
+	selectedCommand, exists := commands[name]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	failures := selectedCommand.run(flags, args)
+	if !selectedCommand.offline {
+		gitlabUtils.LogAPIStats()
+	}
+	if len(failures) > 0 {
+		log.Printf("Run finished with %d failure(s):", len(failures))
+		for _, failure := range failures {
+			log.Println("-", failure)
+		}
+		os.Exit(1)
+	}
+	log.Println("Run complete")
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantName string
+		wantArgs []string
+	}{
+		{name: "Runs all stages without arguments", args: []string{}, wantName: "run", wantArgs: []string{}},
+		{name: "Runs all stages with flags only", args: []string{"-plan"}, wantName: "run", wantArgs: []string{"-plan"}},
+		{name: "Parses command", args: []string{"next", "-count", "3"}, wantName: "next", wantArgs: []string{"-count", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotName, gotArgs := parseCommand(tt.args)
+			if gotName != tt.wantName || !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("parseCommand() = %v, %v, want %v, %v", gotName, gotArgs, tt.wantName, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	t.Setenv("CI_PROJECT_ID", "42")
+	t.Setenv("CI_API_V4_URL", "https://gitlab.example.com/api/v4")
+	t.Setenv("CI_PROJECT_DIR", "")
+	t.Setenv("ISSUE_TEMPLATE_DIR", "")
+	t.Setenv("GITLAB_ISSUE_AUTOMATION_API_TOKEN", "")
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	err := parseFlags(flags, []string{"-project", "group/project", "-token", "token", "-template-dir", "templates"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	workingDir, _ := os.Getwd()
+	want := map[string]string{
+		"CI_PROJECT_ID":                     "group/project",
+		"CI_API_V4_URL":                     "https://gitlab.example.com/api/v4",
+		"GITLAB_ISSUE_AUTOMATION_API_TOKEN": "token",
+		"CI_PROJECT_DIR":                    workingDir,
+		"ISSUE_TEMPLATE_DIR":                "templates",
+	}
+	for name, value := range want {
+		if got := os.Getenv(name); got != value {
+			t.Errorf("parseFlags() set %s = %v, want %v", name, got, value)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package monthlyRecurrance
+
+import (
+	"fmt"
+	types "gitlab-issue-automation/types"
+	"log"
+	"time"
+)
+
+// Periods that are checked for an occurrence of the schedule before giving up,
+// e.g. for schedules on February 30
+const maxPeriods = 1000
+
+// Months or years, numbered continuously so they can be counted
+type period struct {
+	name     string
+	interval int
+	getIndex func(periodTime time.Time) int
+	getStart func(index int, location *time.Location) time.Time
+}
+
+var month = period{
+	name: "month",
+	getIndex: func(periodTime time.Time) int {
+		return periodTime.Year()*12 + int(periodTime.Month()) - 1
+	},
+	getStart: func(index int, location *time.Location) time.Time {
+		return time.Date(index/12, time.Month(index%12+1), 1, 0, 0, 0, 0, location)
+	},
+}
+
+var year = period{
+	name: "year",
+	getIndex: func(periodTime time.Time) int {
+		return periodTime.Year()
+	},
+	getStart: func(index int, location *time.Location) time.Time {
+		return time.Date(index, time.January, 1, 0, 0, 0, 0, location)
+	},
+}
+
+func getPeriod(data *types.Metadata) (period, bool) {
+	recurrence := period{}
+	switch {
+	case data.MonthlyRecurrence > 1:
+		recurrence = month
+		recurrence.interval = data.MonthlyRecurrence
+	case data.YearlyRecurrence > 1:
+		recurrence = year
+		recurrence.interval = data.YearlyRecurrence
+	default:
+		return recurrence, false
+	}
+	return recurrence, true
+}
+
+// Moves the next time to the next occurrence of the schedule in an n-th month
+// or year counted from the anchor of the template, so periods before the
+// anchor count backwards from it. Schedules can skip periods, e.g. monthly
+// schedules on the 31st, so the occurrence is searched again in each period.
+func GetNext(nextTime time.Time, data *types.Metadata, verbose bool) (time.Time, error) {
+	recurrence, exists := getPeriod(data)
+	if !exists {
+		return nextTime, nil
+	}
+	if data.Anchor.IsZero() {
+		return nextTime, fmt.Errorf("no anchor to count the %d-%sly recurrence of %s from", recurrence.interval, recurrence.name, data.TemplateKey)
+	}
+	location := nextTime.Location()
+	anchorIndex := recurrence.getIndex(data.Anchor.In(location))
+	for i := 0; i < maxPeriods; i++ {
+		if nextTime.IsZero() {
+			return nextTime, nil
+		}
+		nextIndex := recurrence.getIndex(nextTime)
+		periodsSinceAnchor := nextIndex - anchorIndex
+		periodsToAdd := (recurrence.interval - periodsSinceAnchor%recurrence.interval) % recurrence.interval
+		if periodsToAdd == 0 {
+			return nextTime, nil
+		}
+		if verbose {
+			log.Println("-- Next", recurrence.interval, recurrence.name+"ly", "occurrence for", data.Title, "in plus", periodsToAdd, recurrence.name+"(s)")
+		}
+		periodStart := recurrence.getStart(nextIndex+periodsToAdd, location)
+		nextTime = data.Schedule.Next(periodStart.Add(-time.Second))
+	}
+	return time.Time{}, fmt.Errorf("no occurrence of %s in every %d-th %s", data.TemplateKey, recurrence.interval, recurrence.name)
+}
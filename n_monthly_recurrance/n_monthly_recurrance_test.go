@@ -0,0 +1,119 @@
+package monthlyRecurrance
+
+import (
+	types "gitlab-issue-automation/types"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+)
+
+func TestGetNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		crontab  string
+		monthly  int
+		yearly   int
+		anchor   string
+		fromTime string
+		want     []string
+	}{
+		{
+			name:     "Every second month on the 31st",
+			crontab:  "0 9 31 * *",
+			monthly:  2,
+			anchor:   "2024-01-15",
+			fromTime: "2024-01-01",
+			want:     []string{"2024-01-31", "2024-03-31", "2024-05-31", "2024-07-31"},
+		},
+		{
+			name:     "Every third month on the 30th skips February",
+			crontab:  "0 9 30 * *",
+			monthly:  3,
+			anchor:   "2023-11-01",
+			fromTime: "2023-11-01",
+			want:     []string{"2023-11-30", "2024-05-30", "2024-08-30", "2024-11-30"},
+		},
+		{
+			name:     "Quarterly across the year boundary",
+			crontab:  "0 9 1 * *",
+			monthly:  3,
+			anchor:   "2024-10-01",
+			fromTime: "2024-09-15",
+			want:     []string{"2024-10-01", "2025-01-01", "2025-04-01", "2025-07-01"},
+		},
+		{
+			name:     "Counts months before the anchor backwards",
+			crontab:  "0 9 1 * *",
+			monthly:  4,
+			anchor:   "2024-06-20",
+			fromTime: "2024-01-15",
+			want:     []string{"2024-02-01", "2024-06-01", "2024-10-01", "2025-02-01"},
+		},
+		{
+			name:     "Every second year with all of its months",
+			crontab:  "0 9 1 * *",
+			yearly:   2,
+			anchor:   "2024-05-01",
+			fromTime: "2024-11-15",
+			want:     []string{"2024-12-01", "2026-01-01", "2026-02-01", "2026-03-01"},
+		},
+		{
+			name:     "Every third year counted from a later anchor",
+			crontab:  "0 9 1 4 *",
+			yearly:   3,
+			anchor:   "2025-04-01",
+			fromTime: "2024-01-01",
+			want:     []string{"2025-04-01", "2028-04-01", "2031-04-01", "2034-04-01"},
+		},
+		{
+			name:     "Every second year on leap days",
+			crontab:  "0 9 29 2 *",
+			yearly:   2,
+			anchor:   "2022-01-01",
+			fromTime: "2023-01-01",
+			want:     []string{"2024-02-29", "2028-02-29", "2032-02-29", "2036-02-29"},
+		},
+		{
+			name:     "Keeps every month without interval",
+			crontab:  "0 9 31 * *",
+			fromTime: "2024-01-01",
+			want:     []string{"2024-01-31", "2024-03-31", "2024-05-31", "2024-07-31"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &types.Metadata{
+				Title:             tt.name,
+				MonthlyRecurrence: tt.monthly,
+				YearlyRecurrence:  tt.yearly,
+				Schedule:          cronexpr.MustParse(tt.crontab),
+			}
+			if tt.anchor != "" {
+				data.Anchor, _ = time.Parse("2006-01-02", tt.anchor)
+			}
+			lastTime, _ := time.Parse("2006-01-02", tt.fromTime)
+			got := []string{}
+			for len(got) < len(tt.want) {
+				nextTime, err := GetNext(data.Schedule.Next(lastTime), data, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, nextTime.Format("2006-01-02"))
+				lastTime = nextTime
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetNext() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNextWithoutAnchor(t *testing.T) {
+	data := &types.Metadata{MonthlyRecurrence: 2, Schedule: cronexpr.MustParse("0 9 1 * *")}
+	_, err := GetNext(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), data, false)
+	if err == nil {
+		t.Error("GetNext() did not fail without anchor")
+	}
+}
@@ -1,45 +1,30 @@
 package weeklyRecurrance
 
 import (
+	"fmt"
 	dateUtils "gitlab-issue-automation/date_utils"
-	gitlabUtils "gitlab-issue-automation/gitlab_utils"
 	types "gitlab-issue-automation/types"
 	"log"
 	"math"
 	"time"
-
-	"github.com/xanzy/go-gitlab"
 )
 
-func GetNext(nextTime time.Time, data *types.Metadata, verbose bool) time.Time {
+// Moves the next time to the next n-th week counted from the anchor of the
+// template, so weeks before the anchor count backwards from it
+func GetNext(nextTime time.Time, data *types.Metadata, verbose bool) (time.Time, error) {
 	if data.WeeklyRecurrence > 1 {
-		git := gitlabUtils.GetGitClient()
-		project := gitlabUtils.GetGitProject()
-		orderBy := "created_at"
-		options := &gitlab.ListProjectIssuesOptions{
-			Search:  &data.Title,
-			OrderBy: &orderBy,
-		}
-		issues, _, err := git.Issues.ListProjectIssues(project.ID, options)
-		if err != nil {
-			log.Fatal(err)
+		if data.Anchor.IsZero() {
+			return nextTime, fmt.Errorf("no anchor to count the %d-weekly recurrence of %s from", data.WeeklyRecurrence, data.TemplateKey)
 		}
-		lastCreationDate := *issues[0].CreatedAt
-		lastCreationWeek := dateUtils.GetStartOfWeek(lastCreationDate)
+		anchorWeek := dateUtils.GetStartOfWeek(data.Anchor.In(nextTime.Location()))
 		nextSingleExecutionWeek := dateUtils.GetStartOfWeek(nextTime)
-		nextNthExecutionWeek := lastCreationWeek
-		for {
-			nextNthExecutionWeek = nextNthExecutionWeek.AddDate(0, 0, 7*data.WeeklyRecurrence)
-			if dateUtils.AreDatesEqual(nextNthExecutionWeek, nextSingleExecutionWeek) || nextNthExecutionWeek.After(nextSingleExecutionWeek) {
-				break
-			}
-		}
-		daysToAdd := math.Round(nextNthExecutionWeek.Sub(nextSingleExecutionWeek).Hours() / 24)
+		// Weeks are rounded since they are an hour shorter or longer on DST changes
+		weeksSinceAnchor := int(math.Round(nextSingleExecutionWeek.Sub(anchorWeek).Hours() / 24 / 7))
+		weeksToAdd := (data.WeeklyRecurrence - weeksSinceAnchor%data.WeeklyRecurrence) % data.WeeklyRecurrence
 		if verbose {
-			weeksToAdd := math.Round(math.Abs(daysToAdd / 7))
 			log.Println("-- Next", data.WeeklyRecurrence, "weekly occurrence for", data.Title, "in plus", weeksToAdd, "week(s)")
 		}
-		nextTime = nextTime.AddDate(0, 0, int(daysToAdd))
+		nextTime = nextTime.AddDate(0, 0, 7*weeksToAdd)
 	}
-	return nextTime
+	return nextTime, nil
 }
@@ -0,0 +1,52 @@
+package occurrenceMarkers
+
+import (
+	"fmt"
+	types "gitlab-issue-automation/types"
+	"regexp"
+	"time"
+)
+
+// Markers are hidden HTML comments in the issue description that record
+// which template occurrence an issue was created for
+const markerFormat = `<!-- gitlab-issue-automation template="%s" occurrence="%s" -->`
+
+var markerPattern = regexp.MustCompile(`<!-- gitlab-issue-automation template="([^"]*)" occurrence="([^"]*)" -->`)
+
+func GetTemplateReference(data *types.Metadata) string {
+	if data.Id != "" {
+		return data.Id
+	}
+	if data.Instance != "" {
+		return data.TemplateKey + "#" + data.Instance
+	}
+	return data.TemplateKey
+}
+
+func GetMarker(data *types.Metadata) string {
+	return fmt.Sprintf(markerFormat, GetTemplateReference(data), data.NextTime.UTC().Format(time.RFC3339))
+}
+
+// Part of the markers of all occurrences of the template, to search for them
+func GetTemplateSearch(data *types.Metadata) string {
+	return fmt.Sprintf(`template="%s"`, GetTemplateReference(data))
+}
+
+func AddMarker(description string, data *types.Metadata) string {
+	if description != "" {
+		description += "\n\n"
+	}
+	return description + GetMarker(data)
+}
+
+func ParseMarker(description string) (string, time.Time, bool) {
+	match := markerPattern.FindStringSubmatch(description)
+	if match == nil {
+		return "", time.Time{}, false
+	}
+	occurrence, err := time.Parse(time.RFC3339, match[2])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return match[1], occurrence, true
+}
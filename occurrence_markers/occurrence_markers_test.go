@@ -0,0 +1,45 @@
+package occurrenceMarkers
+
+import (
+	types "gitlab-issue-automation/types"
+	"testing"
+	"time"
+)
+
+func TestParseMarker(t *testing.T) {
+	occurrence := time.Date(2024, 3, 4, 9, 0, 0, 0, time.FixedZone("CET", 3600))
+	tests := []struct {
+		name          string
+		description   string
+		wantReference string
+		wantFound     bool
+	}{
+		{
+			name:          "Parses marker with template id",
+			description:   AddMarker("Description", &types.Metadata{Id: "weekly-meeting", TemplateKey: "weekly.md", NextTime: occurrence}),
+			wantReference: "weekly-meeting",
+			wantFound:     true,
+		},
+		{
+			name:          "Parses marker with template path",
+			description:   AddMarker("", &types.Metadata{TemplateKey: "team/weekly.md", NextTime: occurrence}),
+			wantReference: "team/weekly.md",
+			wantFound:     true,
+		},
+		{
+			name:        "Ignores descriptions without marker",
+			description: "Description",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reference, gotOccurrence, found := ParseMarker(tt.description)
+			if found != tt.wantFound || reference != tt.wantReference {
+				t.Errorf("ParseMarker() = %v, %v, want %v, %v", reference, found, tt.wantReference, tt.wantFound)
+			}
+			if found && !gotOccurrence.Equal(occurrence) {
+				t.Errorf("ParseMarker() occurrence = %v, want %v", gotOccurrence, occurrence)
+			}
+		})
+	}
+}
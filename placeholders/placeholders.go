@@ -1,6 +1,7 @@
 package placeholders
 
 import (
+	config "gitlab-issue-automation/config"
 	dateUtils "gitlab-issue-automation/date_utils"
 	gitlabUtils "gitlab-issue-automation/gitlab_utils"
 	types "gitlab-issue-automation/types"
@@ -10,26 +11,30 @@ import (
 
 const lastMonthPlaceholder = "{last_month}"
 
-func getLastMonth(data *types.Metadata) string {
-	_, currentMonth, _ := time.Now().Date()
-	lastMonth := currentMonth - 1
-	lastMonthString := lastMonth.String()
-	return lastMonthString
+// The month before the occurrence, counted from the first day of its month
+// so that e.g. March 31 does not become March 3
+func getLastMonth(data *types.Metadata) (string, error) {
+	year, month, _ := data.NextTime.In(config.GetTemplateLocation(data)).Date()
+	lastMonth := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0).Month()
+	return lastMonth.String(), nil
 }
 
 const dateEnDashPlaceholder = "{due_date_en_dash}"
 
-func getEnDashDate(data *types.Metadata) string {
+func getEnDashDate(data *types.Metadata) (string, error) {
 	if data.DueIn != "" {
-		issueDue := gitlabUtils.GetIssueDueDate(data)
+		issueDue, err := gitlabUtils.GetIssueDueDate(data)
+		if err != nil {
+			return "", err
+		}
 		enDashDate := dateUtils.GetEnDashDate(issueDue)
-		return enDashDate
+		return enDashDate, nil
 	} else {
-		return "NO_DUE_DATE_GIVEN"
+		return "NO_DUE_DATE_GIVEN", nil
 	}
 }
 
-var placeholders = map[string]func(*types.Metadata) string{
+var placeholders = map[string]func(*types.Metadata) (string, error){
 	lastMonthPlaceholder:  getLastMonth,
 	dateEnDashPlaceholder: getEnDashDate,
 }
@@ -44,9 +49,44 @@ func applyPlaceholder(data *types.Metadata, placeholder string, replacement stri
 	return data
 }
 
-func ApplyPlaceholders(data *types.Metadata) *types.Metadata {
-	for placeholder, getPlaceholderValue := range placeholders {
-		data = applyPlaceholder(data, placeholder, getPlaceholderValue(data))
+func replaceVariables(text string, variables map[string]string) string {
+	for name, value := range variables {
+		text = strings.ReplaceAll(text, "{"+name+"}", value)
+	}
+	return text
+}
+
+// Variables of template instances are replaced in the title, description,
+// assignees, and labels
+func applyVariables(data *types.Metadata) *types.Metadata {
+	data.Title = replaceVariables(data.Title, data.Variables)
+	data.Description = replaceVariables(data.Description, data.Variables)
+	assignees := []string{}
+	for _, assignee := range data.Assignees {
+		assignees = append(assignees, replaceVariables(assignee, data.Variables))
+	}
+	data.Assignees = assignees
+	labels := []string{}
+	for _, label := range data.Labels {
+		labels = append(labels, replaceVariables(label, data.Variables))
 	}
+	data.Labels = labels
 	return data
 }
+
+func ApplyPlaceholders(data *types.Metadata) (*types.Metadata, error) {
+	if len(data.Variables) > 0 {
+		data = applyVariables(data)
+	}
+	for placeholder, getPlaceholderValue := range placeholders {
+		if !strings.Contains(data.Title, placeholder) && !strings.Contains(data.Description, placeholder) {
+			continue
+		}
+		replacement, err := getPlaceholderValue(data)
+		if err != nil {
+			return data, err
+		}
+		data = applyPlaceholder(data, placeholder, replacement)
+	}
+	return data, nil
+}
@@ -0,0 +1,33 @@
+package placeholders
+
+import (
+	types "gitlab-issue-automation/types"
+	"testing"
+	"time"
+)
+
+func Test_getLastMonth(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		nextTime time.Time
+		location *time.Location
+		want     string
+	}{
+		{name: "Uses month before occurrence", nextTime: time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC), want: "April"},
+		{name: "Wraps around in January", nextTime: time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC), want: "December"},
+		{name: "Skips shorter months", nextTime: time.Date(2026, 3, 31, 9, 0, 0, 0, time.UTC), want: "February"},
+		{name: "Uses timezone of template", nextTime: time.Date(2026, 2, 28, 23, 30, 0, 0, time.UTC), location: berlin, want: "February"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getLastMonth(&types.Metadata{NextTime: tt.nextTime, Location: tt.location})
+			if err != nil || got != tt.want {
+				t.Errorf("getLastMonth() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
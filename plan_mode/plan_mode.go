@@ -0,0 +1,372 @@
+package planMode
+
+import (
+	"encoding/json"
+	"fmt"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	types "gitlab-issue-automation/types"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const CreateIssueAction = "create_issue"
+const UpdateIssueAction = "update_issue"
+const CreateWikiPageAction = "create_wiki_page"
+const SetProjectVariableAction = "set_project_variable"
+const SetSnippetAction = "set_snippet"
+const SetTimeEstimateAction = "set_time_estimate"
+const CreateIssueLinkAction = "create_issue_link"
+const CreateIssueNoteAction = "create_issue_note"
+
+type Mutation struct {
+	Action        string   `json:"action"`
+	IssueId       int      `json:"issueId,omitempty"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description,omitempty"`
+	Labels        []string `json:"labels,omitempty"`
+	AddedLabels   []string `json:"addedLabels,omitempty"`
+	RemovedLabels []string `json:"removedLabels,omitempty"`
+	StateEvent    string   `json:"stateEvent,omitempty"`
+	CreatedAt     string   `json:"createdAt,omitempty"`
+	DueDate       string   `json:"dueDate,omitempty"`
+	AssigneeIds   []int    `json:"assigneeIds,omitempty"`
+	MilestoneId   int      `json:"milestoneId,omitempty"`
+	EpicId        int      `json:"epicId,omitempty"`
+	Weight        *int     `json:"weight,omitempty"`
+	IssueType     string   `json:"issueType,omitempty"`
+	Estimate      string   `json:"estimate,omitempty"`
+	TargetIssueId int      `json:"targetIssueId,omitempty"`
+	LinkType      string   `json:"linkType,omitempty"`
+	Content       string   `json:"content,omitempty"`
+}
+
+// PlanTracker reads from its backend but only records issue and wiki
+// changes instead of sending them, so a run can be reviewed first.
+type PlanTracker struct {
+	Backend   gitlabUtils.Tracker
+	Mutations []Mutation
+	issues    map[int]*gitlab.Issue
+	wikiPages map[string]bool
+	plannedId int
+}
+
+func NewPlanTracker(backend gitlabUtils.Tracker) *PlanTracker {
+	return &PlanTracker{
+		Backend:   backend,
+		issues:    map[int]*gitlab.Issue{},
+		wikiPages: map[string]bool{},
+	}
+}
+
+func (t *PlanTracker) rememberIssues(issues []*gitlab.Issue) []*gitlab.Issue {
+	for index, issue := range issues {
+		if plannedIssue, exists := t.issues[issue.IID]; exists {
+			issues[index] = plannedIssue
+		} else {
+			t.issues[issue.IID] = issue
+		}
+	}
+	return issues
+}
+
+func (t *PlanTracker) ListIssues(orderBy string, sortOrder string, issueState string) ([]*gitlab.Issue, error) {
+	issues, err := t.Backend.ListIssues(orderBy, sortOrder, issueState)
+	if err != nil {
+		return nil, err
+	}
+	return t.rememberIssues(issues), nil
+}
+
+func (t *PlanTracker) SearchIssues(search string) ([]*gitlab.Issue, error) {
+	issues, err := t.Backend.SearchIssues(search)
+	if err != nil {
+		return nil, err
+	}
+	return t.rememberIssues(issues), nil
+}
+
+func (t *PlanTracker) CreateIssue(options *gitlab.CreateIssueOptions) (*gitlab.Issue, error) {
+	t.plannedId--
+	issue := &gitlab.Issue{IID: t.plannedId, ID: t.plannedId, State: "opened"}
+	mutation := Mutation{Action: CreateIssueAction}
+	if options.Title != nil {
+		issue.Title = *options.Title
+		mutation.Title = issue.Title
+	}
+	if options.Description != nil {
+		issue.Description = *options.Description
+		mutation.Description = issue.Description
+	}
+	if options.Labels != nil {
+		issue.Labels = gitlab.Labels(*options.Labels)
+		mutation.Labels = append([]string{}, *options.Labels...)
+	}
+	if options.CreatedAt != nil {
+		issue.CreatedAt = options.CreatedAt
+		mutation.CreatedAt = options.CreatedAt.Format(time.RFC3339)
+	}
+	if options.DueDate != nil {
+		issue.DueDate = options.DueDate
+		mutation.DueDate = options.DueDate.String()
+	}
+	if options.MilestoneID != nil {
+		mutation.MilestoneId = *options.MilestoneID
+	}
+	if options.EpicID != nil {
+		mutation.EpicId = *options.EpicID
+	}
+	if options.Weight != nil {
+		issue.Weight = *options.Weight
+		mutation.Weight = gitlab.Ptr(*options.Weight)
+	}
+	if options.IssueType != nil {
+		issue.IssueType = options.IssueType
+		mutation.IssueType = *options.IssueType
+	}
+	if options.AssigneeIDs != nil {
+		for _, assigneeId := range *options.AssigneeIDs {
+			issue.Assignees = append(issue.Assignees, &gitlab.IssueAssignee{ID: assigneeId})
+		}
+		mutation.AssigneeIds = append([]int{}, *options.AssigneeIDs...)
+	}
+	t.issues[issue.IID] = issue
+	t.Mutations = append(t.Mutations, mutation)
+	return issue, nil
+}
+
+func (t *PlanTracker) UpdateIssue(issueId int, options *gitlab.UpdateIssueOptions) (*gitlab.Issue, error) {
+	issue, exists := t.issues[issueId]
+	if !exists {
+		return nil, fmt.Errorf("issue %d was not loaded before planning an update", issueId)
+	}
+	updatedIssue := *issue
+	mutation := Mutation{Action: UpdateIssueAction, IssueId: issueId, Title: issue.Title}
+	if options.Labels != nil {
+		updatedIssue.Labels = gitlab.Labels(*options.Labels)
+		mutation.AddedLabels = getMissingLabels(updatedIssue.Labels, issue.Labels)
+		mutation.RemovedLabels = getMissingLabels(issue.Labels, updatedIssue.Labels)
+	}
+	if options.StateEvent != nil {
+		mutation.StateEvent = *options.StateEvent
+		switch mutation.StateEvent {
+		case "close":
+			updatedIssue.State = "closed"
+		case "reopen":
+			updatedIssue.State = "opened"
+		}
+	}
+	t.issues[issueId] = &updatedIssue
+	t.Mutations = append(t.Mutations, mutation)
+	return &updatedIssue, nil
+}
+
+func (t *PlanTracker) SetTimeEstimate(issueId int, duration string) error {
+	issue, exists := t.issues[issueId]
+	if !exists {
+		return fmt.Errorf("issue %d was not loaded before planning a time estimate", issueId)
+	}
+	t.Mutations = append(t.Mutations, Mutation{Action: SetTimeEstimateAction, IssueId: issueId, Title: issue.Title, Estimate: duration})
+	return nil
+}
+
+func (t *PlanTracker) CreateIssueLink(issueId int, targetIssueId int, linkType string) error {
+	issue, exists := t.issues[issueId]
+	if !exists {
+		return fmt.Errorf("issue %d was not loaded before planning a link", issueId)
+	}
+	t.Mutations = append(t.Mutations, Mutation{Action: CreateIssueLinkAction, IssueId: issueId, Title: issue.Title, TargetIssueId: targetIssueId, LinkType: linkType})
+	return nil
+}
+
+// Planned issues have negative IDs and only planned links
+func (t *PlanTracker) ListIssueLinks(issueId int) ([]int, error) {
+	linkedIds := []int{}
+	if issueId > 0 {
+		backendIds, err := t.Backend.ListIssueLinks(issueId)
+		if err != nil {
+			return nil, err
+		}
+		linkedIds = append(linkedIds, backendIds...)
+	}
+	for _, mutation := range t.Mutations {
+		if mutation.Action != CreateIssueLinkAction {
+			continue
+		}
+		if mutation.IssueId == issueId {
+			linkedIds = append(linkedIds, mutation.TargetIssueId)
+		}
+		if mutation.TargetIssueId == issueId {
+			linkedIds = append(linkedIds, mutation.IssueId)
+		}
+	}
+	return linkedIds, nil
+}
+
+func (t *PlanTracker) CreateIssueNote(issueId int, body string) error {
+	issue, exists := t.issues[issueId]
+	if !exists {
+		return fmt.Errorf("issue %d was not loaded before planning a note", issueId)
+	}
+	t.Mutations = append(t.Mutations, Mutation{Action: CreateIssueNoteAction, IssueId: issueId, Title: issue.Title, Content: body})
+	return nil
+}
+
+func (t *PlanTracker) GetEpicId(epicIid int) (int, error) {
+	return t.Backend.GetEpicId(epicIid)
+}
+
+func (t *PlanTracker) ListMilestones() ([]*gitlab.Milestone, error) {
+	return t.Backend.ListMilestones()
+}
+
+func (t *PlanTracker) ListIterations() ([]*gitlab.ProjectIteration, error) {
+	return t.Backend.ListIterations()
+}
+
+func (t *PlanTracker) GetWikiPage(title string) (*types.WikiMetadata, error) {
+	if t.wikiPages[title] {
+		return &types.WikiMetadata{Title: title, Slug: title}, nil
+	}
+	return t.Backend.GetWikiPage(title)
+}
+
+func (t *PlanTracker) ListWikiPages() ([]types.WikiMetadata, error) {
+	return t.Backend.ListWikiPages()
+}
+
+func (t *PlanTracker) CreateWikiPage(title string, content string) error {
+	t.wikiPages[title] = true
+	t.Mutations = append(t.Mutations, Mutation{Action: CreateWikiPageAction, Title: title, Content: content})
+	return nil
+}
+
+func (t *PlanTracker) GetLastSuccessfulScheduledPipelineTime(scheduleId int) (time.Time, error) {
+	return t.Backend.GetLastSuccessfulScheduledPipelineTime(scheduleId)
+}
+
+func (t *PlanTracker) GetUserId(username string) (int, error) {
+	return t.Backend.GetUserId(username)
+}
+
+func (t *PlanTracker) GetProjectVariable(key string) (string, error) {
+	return t.Backend.GetProjectVariable(key)
+}
+
+func (t *PlanTracker) SetProjectVariable(key string, value string) error {
+	t.Mutations = append(t.Mutations, Mutation{Action: SetProjectVariableAction, Title: key, Content: value})
+	return nil
+}
+
+func (t *PlanTracker) GetSnippetContent(title string) (string, error) {
+	return t.Backend.GetSnippetContent(title)
+}
+
+func (t *PlanTracker) SetSnippetContent(title string, content string) error {
+	t.Mutations = append(t.Mutations, Mutation{Action: SetSnippetAction, Title: title, Content: content})
+	return nil
+}
+
+func getMissingLabels(labels []string, otherLabels []string) []string {
+	var missingLabels []string
+	for _, label := range labels {
+		labelFound := false
+		for _, otherLabel := range otherLabels {
+			if label == otherLabel {
+				labelFound = true
+				break
+			}
+		}
+		if !labelFound {
+			missingLabels = append(missingLabels, label)
+		}
+	}
+	return missingLabels
+}
+
+func writeLines(writer io.Writer, prefix string, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Fprintln(writer, prefix+line)
+	}
+}
+
+func (t *PlanTracker) PrintDiff(writer io.Writer) {
+	if len(t.Mutations) == 0 {
+		fmt.Fprintln(writer, "No changes planned")
+		return
+	}
+	for _, mutation := range t.Mutations {
+		switch mutation.Action {
+		case CreateIssueAction:
+			fmt.Fprintf(writer, "+ issue '%s'\n", mutation.Title)
+			if mutation.CreatedAt != "" {
+				fmt.Fprintln(writer, "+   created:", mutation.CreatedAt)
+			}
+			if mutation.DueDate != "" {
+				fmt.Fprintln(writer, "+   due:", mutation.DueDate)
+			}
+			if len(mutation.Labels) > 0 {
+				fmt.Fprintln(writer, "+   labels:", strings.Join(mutation.Labels, ", "))
+			}
+			if mutation.IssueType != "" {
+				fmt.Fprintln(writer, "+   type:", mutation.IssueType)
+			}
+			if mutation.Weight != nil {
+				fmt.Fprintln(writer, "+   weight:", *mutation.Weight)
+			}
+			if mutation.MilestoneId != 0 {
+				fmt.Fprintln(writer, "+   milestone ID:", mutation.MilestoneId)
+			}
+			if mutation.EpicId != 0 {
+				fmt.Fprintln(writer, "+   epic ID:", mutation.EpicId)
+			}
+			if len(mutation.AssigneeIds) > 0 {
+				fmt.Fprintln(writer, "+   assignee IDs:", strings.Trim(fmt.Sprint(mutation.AssigneeIds), "[]"))
+			}
+			if mutation.Description != "" {
+				writeLines(writer, "+   | ", mutation.Description)
+			}
+		case UpdateIssueAction:
+			fmt.Fprintf(writer, "~ issue #%d '%s'\n", mutation.IssueId, mutation.Title)
+			for _, label := range mutation.AddedLabels {
+				fmt.Fprintf(writer, "+   label '%s'\n", label)
+			}
+			for _, label := range mutation.RemovedLabels {
+				fmt.Fprintf(writer, "-   label '%s'\n", label)
+			}
+			if mutation.StateEvent != "" {
+				fmt.Fprintln(writer, "~   state:", mutation.StateEvent)
+			}
+		case SetTimeEstimateAction:
+			fmt.Fprintf(writer, "~ issue #%d '%s'\n", mutation.IssueId, mutation.Title)
+			fmt.Fprintln(writer, "+   estimate:", mutation.Estimate)
+		case CreateIssueLinkAction:
+			fmt.Fprintf(writer, "~ issue #%d '%s'\n", mutation.IssueId, mutation.Title)
+			fmt.Fprintf(writer, "+   link %s #%d\n", mutation.LinkType, mutation.TargetIssueId)
+		case CreateIssueNoteAction:
+			fmt.Fprintf(writer, "~ issue #%d '%s'\n", mutation.IssueId, mutation.Title)
+			writeLines(writer, "+   note | ", mutation.Content)
+		case CreateWikiPageAction:
+			fmt.Fprintf(writer, "+ wiki page '%s'\n", mutation.Title)
+			writeLines(writer, "+   | ", mutation.Content)
+		case SetProjectVariableAction:
+			fmt.Fprintf(writer, "~ project variable '%s'\n", mutation.Title)
+			writeLines(writer, "+   | ", mutation.Content)
+		case SetSnippetAction:
+			fmt.Fprintf(writer, "~ snippet '%s'\n", mutation.Title)
+			writeLines(writer, "+   | ", mutation.Content)
+		}
+	}
+}
+
+func (t *PlanTracker) PrintJSON(writer io.Writer) error {
+	mutations := t.Mutations
+	if mutations == nil {
+		mutations = []Mutation{}
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(mutations)
+}
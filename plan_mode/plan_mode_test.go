@@ -0,0 +1,131 @@
+package planMode
+
+import (
+	"bytes"
+	"encoding/json"
+	boardLabels "gitlab-issue-automation/board_labels"
+	constants "gitlab-issue-automation/constants"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	standupNotes "gitlab-issue-automation/standup_notes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestPlanTracker(t *testing.T) {
+	backend := gitlabUtils.NewMemoryTracker()
+	dueDate := gitlab.ISOTime(time.Now().AddDate(0, 0, -1))
+	backend.AddIssue(&gitlab.Issue{Title: "Overdue", DueDate: &dueDate, Labels: gitlab.Labels{constants.ThisWeekLabel}})
+	planTracker := NewPlanTracker(backend)
+	gitlabUtils.SetTracker(planTracker)
+	defer gitlabUtils.SetTracker(nil)
+
+	if failures := boardLabels.AdaptLabels(); len(failures) > 0 {
+		t.Fatal(failures)
+	}
+	err := standupNotes.CreateNotes(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(backend.Issues[0].Labels, gitlab.Labels{constants.ThisWeekLabel}) {
+		t.Errorf("PlanTracker changed labels to %v", backend.Issues[0].Labels)
+	}
+	if len(backend.WikiPages) != 0 {
+		t.Errorf("PlanTracker created wiki pages %v", backend.WikiPages)
+	}
+
+	wantActions := []string{UpdateIssueAction, UpdateIssueAction, CreateWikiPageAction}
+	gotActions := []string{}
+	for _, mutation := range planTracker.Mutations {
+		gotActions = append(gotActions, mutation.Action)
+	}
+	if !reflect.DeepEqual(gotActions, wantActions) {
+		t.Fatalf("PlanTracker recorded %v, want %v", gotActions, wantActions)
+	}
+	if !reflect.DeepEqual(planTracker.Mutations[0].AddedLabels, []string{constants.TodayLabel}) {
+		t.Errorf("PlanTracker added labels %v", planTracker.Mutations[0].AddedLabels)
+	}
+	if !reflect.DeepEqual(planTracker.Mutations[1].RemovedLabels, []string{constants.ThisWeekLabel}) {
+		t.Errorf("PlanTracker removed labels %v", planTracker.Mutations[1].RemovedLabels)
+	}
+
+	diff := new(bytes.Buffer)
+	planTracker.PrintDiff(diff)
+	for _, want := range []string{"~ issue #1 'Overdue'", "+   label '" + constants.TodayLabel + "'", "-   label '" + constants.ThisWeekLabel + "'", "+ wiki page '" + constants.StandupWikiPrefix} {
+		if !strings.Contains(diff.String(), want) {
+			t.Errorf("PrintDiff() = %v, want %v", diff.String(), want)
+		}
+	}
+
+	output := new(bytes.Buffer)
+	err = planTracker.PrintJSON(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mutations []Mutation
+	err = json.Unmarshal(output.Bytes(), &mutations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mutations, planTracker.Mutations) {
+		t.Errorf("PrintJSON() = %v, want %v", mutations, planTracker.Mutations)
+	}
+}
+
+func TestPlanTrackerCreateIssue(t *testing.T) {
+	backend := gitlabUtils.NewMemoryTracker()
+	planTracker := NewPlanTracker(backend)
+	createdAt := time.Date(2024, 3, 5, 6, 0, 0, 0, time.UTC)
+	labels := gitlab.LabelOptions{"label1"}
+	issue, err := planTracker.CreateIssue(&gitlab.CreateIssueOptions{
+		Title:       gitlab.Ptr("Daily chore"),
+		Description: gitlab.Ptr("Do the chore"),
+		CreatedAt:   &createdAt,
+		Labels:      &labels,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.Issues) != 0 {
+		t.Errorf("PlanTracker created issues %v", backend.Issues)
+	}
+	if issue.Title != "Daily chore" {
+		t.Errorf("CreateIssue() = %v", issue)
+	}
+	want := Mutation{Action: CreateIssueAction, Title: "Daily chore", Description: "Do the chore", Labels: []string{"label1"}, CreatedAt: "2024-03-05T06:00:00Z"}
+	if !reflect.DeepEqual(planTracker.Mutations, []Mutation{want}) {
+		t.Errorf("PlanTracker recorded %v, want %v", planTracker.Mutations, want)
+	}
+}
+
+func TestPlanTrackerListIssueLinks(t *testing.T) {
+	backend := gitlabUtils.NewMemoryTracker()
+	first := backend.AddIssue(&gitlab.Issue{Title: "First"})
+	second := backend.AddIssue(&gitlab.Issue{Title: "Second"})
+	err := backend.CreateIssueLink(first.IID, second.IID, constants.RelatesToLinkType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	planTracker := NewPlanTracker(backend)
+	planned, err := planTracker.CreateIssue(&gitlab.CreateIssueOptions{Title: gitlab.Ptr("Planned")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = planTracker.CreateIssueLink(planned.IID, second.IID, constants.RelatesToLinkType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	linkedIds, err := planTracker.ListIssueLinks(second.IID)
+	if err != nil || !reflect.DeepEqual(linkedIds, []int{first.IID, planned.IID}) {
+		t.Errorf("ListIssueLinks() = %v, %v, want existing and planned link", linkedIds, err)
+	}
+	linkedIds, err = planTracker.ListIssueLinks(planned.IID)
+	if err != nil || !reflect.DeepEqual(linkedIds, []int{second.IID}) {
+		t.Errorf("ListIssueLinks() = %v, %v, want planned link", linkedIds, err)
+	}
+}
@@ -0,0 +1,85 @@
+package recurrance_exceptions
+
+import (
+	"fmt"
+	config "gitlab-issue-automation/config"
+	dateUtils "gitlab-issue-automation/date_utils"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	"gitlab-issue-automation/ical"
+	types "gitlab-issue-automation/types"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Replaces definitions with a calendar by one definition per event of the .ics
+// file, their ids are derived from the summaries and start with the id of the
+// definition if it is set
+func importCalendars(exceptionDefinitions []types.ExceptionDefinition) ([]types.ExceptionDefinition, error) {
+	imported := []types.ExceptionDefinition{}
+	for _, definition := range exceptionDefinitions {
+		if definition.Calendar == "" {
+			imported = append(imported, definition)
+			continue
+		}
+		if definition.Start != "" || definition.End != "" {
+			return nil, fmt.Errorf("exception %s: use either calendar or start and end", definition.Id)
+		}
+		calendarDefinitions, err := readCalendar(definition)
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, calendarDefinitions...)
+	}
+	return imported, nil
+}
+
+func readCalendar(definition types.ExceptionDefinition) ([]types.ExceptionDefinition, error) {
+	calendarPath := filepath.Join(gitlabUtils.GetRecurringIssuesPath(), definition.Calendar)
+	contents, err := ioutil.ReadFile(calendarPath)
+	if err != nil {
+		return nil, err
+	}
+	// Events are imported once for all templates, so their days are counted in
+	// the timezone of the project
+	events, err := ical.Parse(string(contents), config.GetLocation())
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", calendarPath, err)
+	}
+	definitions := []types.ExceptionDefinition{}
+	usedIds := map[string]int{}
+	for _, event := range events {
+		id := getEventId(definition.Id, event.Summary)
+		// Events with the same summary, like several vacations, are numbered
+		usedIds[id]++
+		if usedIds[id] > 1 {
+			id = fmt.Sprintf("%s-%d", id, usedIds[id])
+		}
+		layout := dateUtils.ShortISODateLayout
+		if event.Yearly {
+			layout = "YEAR-01-02"
+		}
+		definitions = append(definitions, types.ExceptionDefinition{
+			Id:    id,
+			Start: event.Start.Format(layout),
+			End:   event.End.Format(layout),
+		})
+	}
+	return definitions, nil
+}
+
+// Lowercases the summary and joins its words with dashes, e.g. "Summer
+// Vacation (Spain)" becomes summer-vacation-spain
+func getEventId(prefix string, summary string) string {
+	words := strings.FieldsFunc(strings.ToLower(summary), func(character rune) bool {
+		return !unicode.IsLetter(character) && !unicode.IsDigit(character)
+	})
+	if prefix != "" {
+		words = append([]string{prefix}, words...)
+	}
+	if len(words) == 0 {
+		return "event"
+	}
+	return strings.Join(words, "-")
+}
@@ -3,6 +3,7 @@ package recurrance_exceptions
 import (
 	"errors"
 	"fmt"
+	config "gitlab-issue-automation/config"
 	"gitlab-issue-automation/constants"
 	dateUtils "gitlab-issue-automation/date_utils"
 	gitlabUtils "gitlab-issue-automation/gitlab_utils"
@@ -17,103 +18,208 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-func GetNext(nextTime time.Time, data *types.Metadata, verbose bool) time.Time {
-	if data.Id != "" && exceptionsExist() {
-		exceptions := parseExceptions()
-		matchingExceptions := getExceptionIdsForIssue(exceptions, data.Id)
-		for _, exceptionId := range matchingExceptions {
-			exceptionDefinition := getExceptionDefinition(exceptions.Definitions, exceptionId)
-			startTime, err := time.Parse(dateUtils.ShortISODateLayout, exceptionDefinition.Start)
+const prefixWildcard = "*"
+
+// Loads the exception definitions of the rules for each template or instance
+// once, which GetNext checks for every occurrence
+func Load(instances []*types.Metadata) error {
+	if !exceptionsExist() {
+		return nil
+	}
+	var exceptions *types.RecurranceExceptions
+	for _, data := range instances {
+		if data.Id == "" {
+			continue
+		}
+		if exceptions == nil {
+			parsed, err := parseExceptions()
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
-			endTime, err := time.Parse(dateUtils.ShortISODateLayout, exceptionDefinition.End)
+			exceptions = &parsed
+		}
+		// Rules for a template apply to all of its instances
+		matchingExceptions := getExceptionIdsForIssue(*exceptions, data.Id, data.TemplateId)
+		data.Exceptions = []types.ExceptionDefinition{}
+		for _, exceptionId := range matchingExceptions {
+			definitions, err := getExceptionDefinitions(exceptions.Definitions, exceptionId)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
-			// Need to additioanlly check if dates are equal if day but not time fulfill the before or after condition
-			exceptionApplies := (endTime.After(time.Now()) || dateUtils.AreDatesEqual(endTime, time.Now())) &&
-				(startTime.Before(nextTime) || dateUtils.AreDatesEqual(startTime, nextTime)) &&
-				(endTime.After(nextTime) || dateUtils.AreDatesEqual(endTime, nextTime))
-			if exceptionApplies {
-				nextTime = data.CronExpression.Next(endTime.AddDate(0, 0, 1))
-				if verbose {
-					log.Println("-- Applying exception", exceptionDefinition.Id, "for", data.Id, "from", exceptionDefinition.Start, "to", exceptionDefinition.End)
-					log.Println("-- Setting earliest execution date after exception (ignoring n-weekly recurrances for now)")
-				}
-				break
+			data.Exceptions = append(data.Exceptions, definitions...)
+		}
+	}
+	return nil
+}
+
+func GetNext(nextTime time.Time, data *types.Metadata, verbose bool) (time.Time, error) {
+	// Exception dates are days in the timezone of the template, and they are
+	// compared with the occurrence, which might have been missed while
+	// catching up
+	occurrenceTime := nextTime.In(config.GetTemplateLocation(data))
+	for _, exceptionDefinition := range data.Exceptions {
+		exceptionDefinition, endTime, err := getApplyingDefinition(exceptionDefinition, occurrenceTime)
+		if err != nil {
+			return nextTime, err
+		}
+		if !endTime.IsZero() {
+			nextTime = data.Schedule.Next(endTime.AddDate(0, 0, 1))
+			if verbose {
+				log.Println("-- Applying exception", exceptionDefinition.Id, "for", data.Id, "from", exceptionDefinition.Start, "to", exceptionDefinition.End)
+				log.Println("-- Setting earliest execution date after exception (ignoring n-weekly recurrances for now)")
 			}
+			break
 		}
 	}
-	return nextTime
+	return nextTime, nil
 }
 
-func getExceptionIdsForIssue(exceptions types.RecurranceExceptions, issueId string) []string {
+func getExceptionIdsForIssue(exceptions types.RecurranceExceptions, issueIds ...string) []string {
 	matchingExceptions := []string{}
 	for _, rule := range exceptions.Rules {
-		if rule.Issue == issueId {
-			matchingExceptions = append(matchingExceptions, rule.Exceptions...)
+		for _, issueId := range issueIds {
+			if issueId != "" && rule.Issue == issueId {
+				matchingExceptions = append(matchingExceptions, rule.Exceptions...)
+				break
+			}
 		}
 	}
 	return matchingExceptions
 }
 
-func getExceptionDefinition(exceptionDefinitions []types.ExceptionDefinition, exceptionId string) types.ExceptionDefinition {
-	definitionFound := false
-	var exceptionDefinition types.ExceptionDefinition
+// Fills in the year of the occurrence, or the year before for yearly
+// exceptions over the new year, and checks whether the occurrence is in the
+// exception, returns the end of the exception if it does
+func getApplyingDefinition(exceptionDefinition types.ExceptionDefinition, occurrenceTime time.Time) (types.ExceptionDefinition, time.Time, error) {
+	for _, yearTime := range []time.Time{occurrenceTime, occurrenceTime.AddDate(-1, 0, 0)} {
+		filledDefinition, err := fillInYearPlaceholdes(exceptionDefinition, yearTime)
+		if err != nil {
+			return filledDefinition, time.Time{}, err
+		}
+		startTime, err := time.ParseInLocation(dateUtils.ShortISODateLayout, filledDefinition.Start, occurrenceTime.Location())
+		if err != nil {
+			return filledDefinition, time.Time{}, err
+		}
+		endTime, err := time.ParseInLocation(dateUtils.ShortISODateLayout, filledDefinition.End, occurrenceTime.Location())
+		if err != nil {
+			return filledDefinition, time.Time{}, err
+		}
+		// Need to additioanlly check if dates are equal if day but not time fulfill the before or after condition
+		applies := (startTime.Before(occurrenceTime) || dateUtils.AreDatesEqual(startTime, occurrenceTime)) &&
+			(endTime.After(occurrenceTime) || dateUtils.AreDatesEqual(endTime, occurrenceTime))
+		if applies {
+			return filledDefinition, endTime, nil
+		}
+	}
+	return exceptionDefinition, time.Time{}, nil
+}
+
+// Exception ids ending with * match all definitions starting with the part
+// before it, e.g. vacation-* for all events of an imported vacation calendar
+func getExceptionDefinitions(exceptionDefinitions []types.ExceptionDefinition, exceptionId string) ([]types.ExceptionDefinition, error) {
+	prefix := strings.TrimSuffix(exceptionId, prefixWildcard)
+	isPrefix := strings.HasSuffix(exceptionId, prefixWildcard)
+	matchingDefinitions := []types.ExceptionDefinition{}
 	for _, definition := range exceptionDefinitions {
-		if exceptionId == definition.Id {
-			definitionFound = true
-			exceptionDefinition = definition
+		if exceptionId == definition.Id || (isPrefix && strings.HasPrefix(definition.Id, prefix)) {
+			matchingDefinitions = append(matchingDefinitions, definition)
 		}
 	}
-	if !definitionFound {
-		log.Fatal(fmt.Errorf("unknown exception definition %s", exceptionId))
+	if len(matchingDefinitions) == 0 && isPrefix {
+		return nil, fmt.Errorf("no exception definition starts with %s", prefix)
+	}
+	if len(matchingDefinitions) == 0 {
+		return nil, fmt.Errorf("unknown exception definition %s", exceptionId)
 	}
-	return fillInYearPlaceholdes(exceptionDefinition)
+	return matchingDefinitions, nil
 }
 
-func fillInYearPlaceholdes(exceptionDefinition types.ExceptionDefinition) types.ExceptionDefinition {
+func fillInYearPlaceholdes(exceptionDefinition types.ExceptionDefinition, currentTime time.Time) (types.ExceptionDefinition, error) {
 	const YearPlaceholder = "YEAR"
 	if (strings.Contains(exceptionDefinition.Start, YearPlaceholder) &&
 		!strings.Contains(exceptionDefinition.End, YearPlaceholder)) ||
 		(!strings.Contains(exceptionDefinition.Start, YearPlaceholder) &&
 			strings.Contains(exceptionDefinition.End, YearPlaceholder)) {
-		log.Fatal(errors.New("please use the YEAR place holder always for both dates in the exception definition"))
+		return exceptionDefinition, errors.New("please use the YEAR place holder always for both dates in the exception definition")
 	}
 	if strings.Contains(exceptionDefinition.Start, YearPlaceholder) &&
 		strings.Contains(exceptionDefinition.End, YearPlaceholder) {
-		currentYear := time.Now().Format(dateUtils.YearDateLayout)
+		currentYear := currentTime.Format(dateUtils.YearDateLayout)
 		exceptionDefinition.Start = strings.ReplaceAll(exceptionDefinition.Start, YearPlaceholder, currentYear)
 		exceptionDefinition.End = strings.ReplaceAll(exceptionDefinition.End, YearPlaceholder, currentYear)
 		startTime, err := time.Parse(dateUtils.ShortISODateLayout, exceptionDefinition.Start)
 		if err != nil {
-			log.Fatal(err)
+			return exceptionDefinition, err
 		}
 		endTime, err := time.Parse(dateUtils.ShortISODateLayout, exceptionDefinition.End)
 		if err != nil {
-			log.Fatal(err)
+			return exceptionDefinition, err
 		}
 		if startTime.Month() > endTime.Month() {
-			nextYear := time.Now().AddDate(1, 0, 0).Format(dateUtils.YearDateLayout)
+			nextYear := currentTime.AddDate(1, 0, 0).Format(dateUtils.YearDateLayout)
 			exceptionDefinition.End = strings.ReplaceAll(exceptionDefinition.End, currentYear, nextYear)
 		}
 	}
-	return exceptionDefinition
+	return exceptionDefinition, nil
 }
 
-func parseExceptions() types.RecurranceExceptions {
+func parseExceptions() (types.RecurranceExceptions, error) {
 	exceptionsPath := getExceptionsPath()
 	exceptions := types.RecurranceExceptions{}
 	source, err := ioutil.ReadFile(exceptionsPath)
 	if err != nil {
-		log.Fatal(err)
+		return exceptions, err
 	}
 	err = yaml.Unmarshal(source, &exceptions)
 	if err != nil {
-		log.Fatal(err)
+		return exceptions, fmt.Errorf("could not parse %s: %w", exceptionsPath, err)
+	}
+	exceptions.Definitions, err = importCalendars(exceptions.Definitions)
+	if err != nil {
+		return exceptions, fmt.Errorf("%s: %w", exceptionsPath, err)
 	}
-	return exceptions
+	return exceptions, nil
+}
+
+// Checks that all exception definitions have valid dates and that all rules
+// reference existing definitions
+func Validate() []error {
+	failures := []error{}
+	if !exceptionsExist() {
+		return failures
+	}
+	exceptions, err := parseExceptions()
+	if err != nil {
+		return append(failures, err)
+	}
+	for _, definition := range exceptions.Definitions {
+		err = validateDates(definition)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("exception %s: %w", definition.Id, err))
+		}
+	}
+	for _, rule := range exceptions.Rules {
+		for _, exceptionId := range rule.Exceptions {
+			_, err = getExceptionDefinitions(exceptions.Definitions, exceptionId)
+			if err != nil {
+				failures = append(failures, fmt.Errorf("exception rule for %s: %w", rule.Issue, err))
+			}
+		}
+	}
+	return failures
+}
+
+func validateDates(exceptionDefinition types.ExceptionDefinition) error {
+	exceptionDefinition, err := fillInYearPlaceholdes(exceptionDefinition, time.Now())
+	if err != nil {
+		return err
+	}
+	_, err = time.Parse(dateUtils.ShortISODateLayout, exceptionDefinition.Start)
+	if err != nil {
+		return err
+	}
+	_, err = time.Parse(dateUtils.ShortISODateLayout, exceptionDefinition.End)
+	return err
 }
 
 func exceptionsExist() bool {
@@ -123,19 +229,22 @@ func exceptionsExist() bool {
 }
 
 func getExceptionsPath() string {
-	return path.Join(gitlabUtils.GetRecurringIssuesPath(), "recurrance_exceptions.yml")
+	return path.Join(gitlabUtils.GetRecurringIssuesPath(), constants.ExceptionsFileName)
 }
 
-func IsVacationUpcoming() bool {
+func IsVacationUpcoming() (bool, error) {
 	vacationUpcoming := false
-	currentTime := time.Now()
+	currentTime := time.Now().In(config.GetLocation())
 	if exceptionsExist() {
-		exceptions := parseExceptions().Definitions
-		for _, exception := range exceptions {
+		exceptions, err := parseExceptions()
+		if err != nil {
+			return vacationUpcoming, err
+		}
+		for _, exception := range exceptions.Definitions {
 			if strings.HasPrefix(exception.Id, constants.VacationExceptionPrefix) {
-				vacationStart, err := time.Parse(dateUtils.ShortISODateLayout, exception.Start)
+				vacationStart, err := time.ParseInLocation(dateUtils.ShortISODateLayout, exception.Start, currentTime.Location())
 				if err != nil {
-					log.Fatal(err)
+					return vacationUpcoming, err
 				}
 				if vacationStart.After(currentTime) || dateUtils.AreDatesEqual(currentTime, vacationStart) {
 					// TODO: Get last workday before vacation
@@ -144,5 +253,5 @@ func IsVacationUpcoming() bool {
 			}
 		}
 	}
-	return vacationUpcoming
+	return vacationUpcoming, nil
 }
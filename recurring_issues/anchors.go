@@ -0,0 +1,79 @@
+package recurringIssues
+
+import (
+	"errors"
+	"fmt"
+	config "gitlab-issue-automation/config"
+	dateUtils "gitlab-issue-automation/date_utils"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	types "gitlab-issue-automation/types"
+	"time"
+)
+
+// Interval recurrences like every n-th week are counted from an anchor
+func usesAnchor(data *types.Metadata) bool {
+	return data.WeeklyRecurrence > 1 || data.MonthlyRecurrence > 1 || data.YearlyRecurrence > 1
+}
+
+func validateRecurrence(data *types.Metadata) error {
+	recurrences := map[string]int{
+		"weeklyRecurrence":  data.WeeklyRecurrence,
+		"monthlyRecurrence": data.MonthlyRecurrence,
+		"yearlyRecurrence":  data.YearlyRecurrence,
+	}
+	intervals := 0
+	for name, interval := range recurrences {
+		if interval < 0 {
+			return fmt.Errorf("invalid %s %d", name, interval)
+		}
+		if interval > 1 {
+			intervals++
+		}
+	}
+	if intervals > 1 {
+		return errors.New("use only one of weeklyRecurrence, monthlyRecurrence, or yearlyRecurrence")
+	}
+	if data.AnchorDate != "" {
+		_, err := parseAnchorDate(data)
+		return err
+	}
+	return nil
+}
+
+func parseAnchorDate(data *types.Metadata) (time.Time, error) {
+	anchor, err := time.ParseInLocation(dateUtils.ShortISODateLayout, data.AnchorDate, config.GetTemplateLocation(data))
+	if err != nil {
+		return anchor, fmt.Errorf("invalid anchorDate '%s', use YYYY-MM-DD", data.AnchorDate)
+	}
+	return anchor, nil
+}
+
+// Sets the anchor to the anchorDate of the template. Without it, the last
+// occurrence in the state is used, then the latest occurrence marker before
+// the last time, and new templates start with their first occurrence after
+// the last time. The state is nil for commands that do not load it.
+func setAnchor(data *types.Metadata, lastTime time.Time, state *types.RunState) error {
+	if !usesAnchor(data) {
+		return nil
+	}
+	if data.AnchorDate != "" {
+		anchor, err := parseAnchorDate(data)
+		data.Anchor = anchor
+		return err
+	}
+	if state != nil {
+		if occurrence, exists := state.Occurrences[getOccurrenceKey(data)]; exists {
+			data.Anchor = occurrence
+			return nil
+		}
+	}
+	occurrence, err := gitlabUtils.GetPreviousOccurrenceTime(data, lastTime)
+	if err != nil {
+		return err
+	}
+	if occurrence.IsZero() {
+		occurrence = data.Schedule.Next(lastTime.In(config.GetTemplateLocation(data)))
+	}
+	data.Anchor = occurrence
+	return nil
+}
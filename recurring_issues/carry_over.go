@@ -0,0 +1,55 @@
+package recurringIssues
+
+import (
+	"regexp"
+	"strings"
+)
+
+const carriedOverHeading = "## Carried over"
+
+var checklistItemPattern = regexp.MustCompile(`^\s*[*-] \[[ xX]\] (.+)$`)
+var uncheckedItemPattern = regexp.MustCompile(`^\s*[*-] \[ \] (.+)$`)
+
+func getChecklistItems(description string, pattern *regexp.Regexp) []string {
+	items := []string{}
+	for _, line := range strings.Split(description, "\n") {
+		match := pattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match != nil {
+			items = append(items, strings.TrimSpace(match[1]))
+		}
+	}
+	return items
+}
+
+func containsItem(items []string, wantedItem string) bool {
+	for _, item := range items {
+		if item == wantedItem {
+			return true
+		}
+	}
+	return false
+}
+
+// Appends the unchecked items of the previous description that are not part
+// of the description yet, checked or not
+func addCarriedOverItems(description string, previousDescription string) string {
+	existingItems := getChecklistItems(description, checklistItemPattern)
+	carriedOverItems := []string{}
+	for _, item := range getChecklistItems(previousDescription, uncheckedItemPattern) {
+		if !containsItem(existingItems, item) && !containsItem(carriedOverItems, item) {
+			carriedOverItems = append(carriedOverItems, item)
+		}
+	}
+	if len(carriedOverItems) == 0 {
+		return description
+	}
+	section := carriedOverHeading + "\n\n"
+	for _, item := range carriedOverItems {
+		section += "* [ ] " + item + "\n"
+	}
+	description = strings.TrimRight(description, "\n")
+	if description != "" {
+		description += "\n\n"
+	}
+	return description + section
+}
@@ -0,0 +1,146 @@
+package recurringIssues
+
+import (
+	"errors"
+	"fmt"
+	"gitlab-issue-automation/constants"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	types "gitlab-issue-automation/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/ericaro/frontmatter"
+	"gopkg.in/yaml.v2"
+)
+
+type fields = map[string]interface{}
+
+// Front matter of a template as raw fields, to know which fields are set
+type templateFields struct {
+	Fields  fields `yaml:",inline"`
+	Content string `fm:"content" yaml:"-"`
+}
+
+func getListMerge(values ...fields) (string, error) {
+	for _, value := range values {
+		listMerge, exists := value["listMerge"]
+		if !exists {
+			continue
+		}
+		switch listMerge {
+		case constants.ListMergeAppend, constants.ListMergeReplace:
+			return listMerge.(string), nil
+		}
+		return "", fmt.Errorf("unknown listMerge '%v', use %s or %s", listMerge, constants.ListMergeAppend, constants.ListMergeReplace)
+	}
+	return constants.ListMergeAppend, nil
+}
+
+func containsValue(values []interface{}, wantedValue interface{}) bool {
+	for _, value := range values {
+		if reflect.DeepEqual(value, wantedValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields of override take precedence, lists are appended to the lists of base
+// unless override or base sets listMerge to replace
+func mergeFields(base fields, override fields) (fields, error) {
+	listMerge, err := getListMerge(override, base)
+	if err != nil {
+		return nil, err
+	}
+	merged := fields{}
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		baseList, baseIsList := merged[key].([]interface{})
+		overrideList, overrideIsList := value.([]interface{})
+		if baseIsList && overrideIsList && listMerge == constants.ListMergeAppend {
+			mergedList := append([]interface{}{}, baseList...)
+			for _, item := range overrideList {
+				if !containsValue(mergedList, item) {
+					mergedList = append(mergedList, item)
+				}
+			}
+			value = mergedList
+		}
+		merged[key] = value
+	}
+	return merged, nil
+}
+
+// Merges the defaults files from the templates directory down to the
+// directory of the template
+func readDefaults(templatePath string) (fields, error) {
+	templatesPath := gitlabUtils.GetRecurringIssuesPath()
+	relativeDir, err := filepath.Rel(templatesPath, filepath.Dir(templatePath))
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{templatesPath}
+	if relativeDir != "." && !strings.HasPrefix(relativeDir, "..") {
+		dir := templatesPath
+		for _, segment := range strings.Split(relativeDir, string(filepath.Separator)) {
+			dir = filepath.Join(dir, segment)
+			dirs = append(dirs, dir)
+		}
+	}
+	defaults := fields{}
+	for _, dir := range dirs {
+		defaultsPath := filepath.Join(dir, constants.DefaultsFileName)
+		contents, err := ioutil.ReadFile(defaultsPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dirDefaults := fields{}
+		err = yaml.Unmarshal(contents, &dirDefaults)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", defaultsPath, err)
+		}
+		defaults, err = mergeFields(defaults, dirDefaults)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", defaultsPath, err)
+		}
+	}
+	return defaults, nil
+}
+
+func parseTemplate(templatePath string, contents []byte) (*types.Metadata, error) {
+	defaults, err := readDefaults(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(defaults) == 0 {
+		return parseMetadata(contents)
+	}
+	template := templateFields{}
+	err = frontmatter.Unmarshal(contents, &template)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergeFields(defaults, template.Fields)
+	if err != nil {
+		return nil, err
+	}
+	mergedYaml, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	data := new(types.Metadata)
+	err = yaml.Unmarshal(mergedYaml, data)
+	if err != nil {
+		return nil, err
+	}
+	data.Description = template.Content
+	return data, nil
+}
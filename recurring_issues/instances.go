@@ -0,0 +1,61 @@
+package recurringIssues
+
+import (
+	"fmt"
+	types "gitlab-issue-automation/types"
+	"strings"
+)
+
+const instanceIdVariable = "id"
+
+// Expands a template with instances into one template per instance, each with
+// the variables of its instance. Instances of templates with an id get the id
+// <template id>-<instance id> to define exceptions per instance.
+func expandInstances(template *types.Metadata) ([]*types.Metadata, error) {
+	if len(template.Instances) == 0 {
+		return []*types.Metadata{template}, nil
+	}
+	instances := []*types.Metadata{}
+	instanceIds := map[string]bool{}
+	for index, variables := range template.Instances {
+		instanceId := variables[instanceIdVariable]
+		if instanceId == "" {
+			return nil, fmt.Errorf("instance %d: missing id", index+1)
+		}
+		if strings.ContainsAny(instanceId, `"#`) {
+			return nil, fmt.Errorf("instance %d: id '%s' must not contain \" or #", index+1, instanceId)
+		}
+		if instanceIds[instanceId] {
+			return nil, fmt.Errorf("instance %d: duplicate id '%s'", index+1, instanceId)
+		}
+		instanceIds[instanceId] = true
+		instance := *template
+		instance.Assignees = append([]string{}, template.Assignees...)
+		instance.Labels = append([]string{}, template.Labels...)
+		instance.Instances = nil
+		instance.Instance = instanceId
+		instance.TemplateId = template.Id
+		if template.Id != "" {
+			instance.Id = template.Id + "-" + instanceId
+		}
+		instance.Variables = variables
+		instances = append(instances, &instance)
+	}
+	return instances, nil
+}
+
+// Name of the template or instance in logs
+func getDisplayName(data *types.Metadata, name string) string {
+	if data.Instance == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, data.Instance)
+}
+
+// Key of the template or instance in the run state
+func getOccurrenceKey(data *types.Metadata) string {
+	if data.Instance == "" {
+		return data.TemplateKey
+	}
+	return data.TemplateKey + "#" + data.Instance
+}
@@ -0,0 +1,77 @@
+package recurringIssues
+
+import (
+	"fmt"
+	config "gitlab-issue-automation/config"
+	"gitlab-issue-automation/constants"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	types "gitlab-issue-automation/types"
+	"log"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func getPreviousOccurrencePolicy(data *types.Metadata) (string, error) {
+	switch data.PreviousPolicy {
+	case constants.PreviousOccurrenceClose, constants.PreviousOccurrenceLabel, constants.PreviousOccurrenceKeep:
+		return data.PreviousPolicy, nil
+	case "":
+		return constants.PreviousOccurrenceKeep, nil
+	}
+	return "", fmt.Errorf("unknown previousOccurrence policy '%s', use one of %s, %s, or %s", data.PreviousPolicy, constants.PreviousOccurrenceClose, constants.PreviousOccurrenceLabel, constants.PreviousOccurrenceKeep)
+}
+
+func usesPreviousOccurrence(data *types.Metadata) bool {
+	policy, _ := getPreviousOccurrencePolicy(data)
+	return data.LinkPrevious || data.NotePrevious || data.CarryOver || policy == constants.PreviousOccurrenceClose || policy == constants.PreviousOccurrenceLabel
+}
+
+// Closes the open issue of the previous occurrence with a comment, or marks
+// it with the missed label
+func applyPreviousOccurrencePolicy(policy string, issue *gitlab.Issue, previousIssue *gitlab.Issue) error {
+	if previousIssue.State != "opened" {
+		return nil
+	}
+	switch policy {
+	case constants.PreviousOccurrenceClose:
+		log.Println("-- Closing previous occurrence", previousIssue.IID)
+		err := gitlabUtils.CreateIssueNote(previousIssue.IID, fmt.Sprintf("Closed automatically because the next occurrence #%d was created.", issue.IID))
+		if err != nil {
+			return err
+		}
+		_, err = gitlabUtils.UpdateIssue(previousIssue.IID, &gitlab.UpdateIssueOptions{StateEvent: gitlab.Ptr("close")})
+		return err
+	case constants.PreviousOccurrenceLabel:
+		missedLabel := config.Get().Labels.Missed
+		for _, label := range previousIssue.Labels {
+			if label == missedLabel {
+				return nil
+			}
+		}
+		log.Println("-- Labelling previous occurrence", previousIssue.IID, "as missed")
+		labels := gitlab.LabelOptions(append(append([]string{}, previousIssue.Labels...), missedLabel))
+		_, err := gitlabUtils.UpdateIssue(previousIssue.IID, &gitlab.UpdateIssueOptions{Labels: &labels})
+		return err
+	}
+	return nil
+}
+
+// Applies the settings of the template for the previous occurrence once the
+// new issue was created or found to exist
+func handlePreviousOccurrence(data *types.Metadata, issue *gitlab.Issue, previousIssue *gitlab.Issue, existed bool) error {
+	if previousIssue == nil {
+		return nil
+	}
+	if data.LinkPrevious || data.NotePrevious {
+		log.Println("-- Linking issue", issue.IID, "to previous occurrence", previousIssue.IID)
+		err := gitlabUtils.LinkPreviousOccurrence(issue, previousIssue, data.NotePrevious, existed)
+		if err != nil {
+			return err
+		}
+	}
+	policy, err := getPreviousOccurrencePolicy(data)
+	if err != nil {
+		return err
+	}
+	return applyPreviousOccurrencePolicy(policy, issue, previousIssue)
+}
@@ -1,28 +1,86 @@
 package recurringIssues
 
 import (
+	"errors"
+	"fmt"
+	businessDays "gitlab-issue-automation/business_days"
+	config "gitlab-issue-automation/config"
 	"gitlab-issue-automation/constants"
 	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	nMonthlyRecurrance "gitlab-issue-automation/n_monthly_recurrance"
 	nWeeklyRecurrance "gitlab-issue-automation/n_weekly_recurrance"
 	placeholders "gitlab-issue-automation/placeholders"
 	recurranceExceptions "gitlab-issue-automation/recurrance_exceptions"
+	rrule "gitlab-issue-automation/rrule"
+	stateStore "gitlab-issue-automation/state_store"
 	types "gitlab-issue-automation/types"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/ericaro/frontmatter"
 	"github.com/gorhill/cronexpr"
+	"github.com/xanzy/go-gitlab"
 )
 
-func ProcessIssueFiles(lastRunTime time.Time) {
-	err := filepath.Walk(gitlabUtils.GetRecurringIssuesPath(), processIssueFile(lastRunTime))
-	if err != nil {
-		log.Fatal(err)
-	}
+// Processes all templates, a failing template does not stop the others
+func ProcessIssueFiles(lastRunTime time.Time, state *types.RunState) []error {
+	return walkTemplates(func(path string, info os.FileInfo) error {
+		log.Println("- Checking", path)
+		return processTemplate(path, info, lastRunTime, state)
+	})
+}
+
+// Checks all templates without calling the API
+func ValidateIssueFiles() []error {
+	return walkTemplates(func(path string, info os.FileInfo) error {
+		log.Println("- Validating", path)
+		return validateTemplate(path)
+	})
+}
+
+// Returns the next occurrences of all templates after the given time, sorted
+// by time
+func GetNextOccurrences(fromTime time.Time, count int, state *types.RunState) ([]*types.Metadata, []error) {
+	occurrences := []*types.Metadata{}
+	failures := walkTemplates(func(path string, info os.FileInfo) error {
+		verbose := false
+		instances, err := readRecurringIssues(path)
+		if err != nil {
+			return err
+		}
+		for _, instance := range instances {
+			err = setAnchor(instance, fromTime, state)
+			if err != nil {
+				return err
+			}
+			lastTime := fromTime
+			for i := 0; i < count; i++ {
+				nextTime, err := getNextExecutionTime(lastTime, instance, verbose)
+				if err != nil {
+					return err
+				}
+				if !nextTime.After(lastTime) {
+					break
+				}
+				occurrence, err := getOccurrence(instance, nextTime)
+				if err != nil {
+					return err
+				}
+				occurrences = append(occurrences, occurrence)
+				lastTime = nextTime
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].NextTime.Before(occurrences[j].NextTime)
+	})
+	return occurrences, failures
 }
 
 func parseMetadata(contents []byte) (*types.Metadata, error) {
@@ -34,37 +92,261 @@ func parseMetadata(contents []byte) (*types.Metadata, error) {
 	return data, nil
 }
 
-func getNextExecutionTime(lastTime time.Time, data *types.Metadata, verbose bool) time.Time {
-	nextTime := data.CronExpression.Next(lastTime)
-	nextTime = nWeeklyRecurrance.GetNext(nextTime, data, verbose)
-	nextTime = recurranceExceptions.GetNext(nextTime, data, verbose)
-	return nextTime
+// Occurrences that are shifted to a workday before or at the last time are
+// skipped, so the occurrences of some schedules are merged. Shifting gives up
+// after a year of daily occurrences.
+const maxShiftedOccurrences = 366
+
+func getNextExecutionTime(lastTime time.Time, data *types.Metadata, verbose bool) (time.Time, error) {
+	if data.Shift != constants.ShiftNext && data.Shift != constants.ShiftPrevious {
+		return getScheduledTime(lastTime, data, verbose)
+	}
+	scheduledTime := lastTime
+	for i := 0; i < maxShiftedOccurrences; i++ {
+		var err error
+		scheduledTime, err = getScheduledTime(scheduledTime, data, verbose)
+		if err != nil || scheduledTime.IsZero() {
+			return scheduledTime, err
+		}
+		nextTime := data.Calendar.Shift(scheduledTime, data.Shift)
+		if nextTime.After(lastTime) {
+			if verbose && !nextTime.Equal(scheduledTime) {
+				log.Println("-- Shifting occurrence from", scheduledTime.Format(time.RFC3339), "to", data.Shift, "workday", nextTime.Format(time.RFC3339))
+			}
+			return nextTime, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no occurrence after %s can be shifted to a workday", lastTime.Format(time.RFC3339))
+}
+
+// Next occurrence of the schedule, with n-weekly, n-monthly, or n-yearly
+// recurrence and exceptions
+func getScheduledTime(lastTime time.Time, data *types.Metadata, verbose bool) (time.Time, error) {
+	nextTime := data.Schedule.Next(lastTime.In(config.GetTemplateLocation(data)))
+	if nextTime.IsZero() {
+		return nextTime, nil
+	}
+	nextTime, err := nWeeklyRecurrance.GetNext(nextTime, data, verbose)
+	if err != nil {
+		return nextTime, err
+	}
+	nextTime, err = nMonthlyRecurrance.GetNext(nextTime, data, verbose)
+	if err != nil || nextTime.IsZero() {
+		return nextTime, err
+	}
+	return recurranceExceptions.GetNext(nextTime, data, verbose)
 }
 
-func GetRecurringIssue(path string, lastTime time.Time, verbose bool) (*types.Metadata, error) {
+func readRecurringIssue(path string) (*types.Metadata, error) {
 	recurringIssue := new(types.Metadata)
 	contents, err := ioutil.ReadFile(path)
 	if err != nil {
 		return recurringIssue, err
 	}
-	recurringIssue, err = parseMetadata(contents)
+	recurringIssue, err = parseTemplate(path, contents)
 	if err != nil {
 		return recurringIssue, err
 	}
-	cronExpression, err := cronexpr.Parse(recurringIssue.Crontab)
+	recurringIssue.Location = config.GetLocation()
+	if recurringIssue.Timezone != "" {
+		recurringIssue.Location, err = config.LoadLocation(recurringIssue.Timezone)
+		if err != nil {
+			return recurringIssue, err
+		}
+	}
+	recurringIssue.Schedule, err = parseSchedule(recurringIssue)
 	if err != nil {
 		return recurringIssue, err
 	}
-	recurringIssue.CronExpression = *cronExpression
-	recurringIssue.NextTime = getNextExecutionTime(lastTime, recurringIssue, verbose)
-	recurringIssue = placeholders.ApplyPlaceholders(recurringIssue)
+	recurringIssue.TemplateKey = GetTemplateKey(path)
 	return recurringIssue, nil
 }
 
-func processIssueFile(lastTime time.Time) filepath.WalkFunc {
-	return func(path string, info os.FileInfo, err error) error {
+// Templates are scheduled either by crontab or by rrule
+func parseSchedule(data *types.Metadata) (types.Schedule, error) {
+	if data.RRule == "" {
+		return cronexpr.Parse(data.Crontab)
+	}
+	if data.Crontab != "" {
+		return nil, errors.New("use either crontab or rrule")
+	}
+	return rrule.Parse(data.RRule, data.Location)
+}
+
+// Reads a template and expands its instances, with the calendar and
+// exceptions for all of their occurrences
+func readRecurringIssues(path string) ([]*types.Metadata, error) {
+	recurringIssue, err := readRecurringIssue(path)
+	if err != nil {
+		return nil, err
+	}
+	if recurringIssue.Shift == constants.ShiftNext || recurringIssue.Shift == constants.ShiftPrevious {
+		recurringIssue.Calendar, err = gitlabUtils.GetCalendar()
 		if err != nil {
-			return err
+			return nil, err
+		}
+	}
+	instances, err := expandInstances(recurringIssue)
+	if err != nil {
+		return nil, err
+	}
+	return instances, recurranceExceptions.Load(instances)
+}
+
+func getOccurrence(template *types.Metadata, nextTime time.Time) (*types.Metadata, error) {
+	occurrence := *template
+	occurrence.Assignees = append([]string{}, template.Assignees...)
+	occurrence.Labels = append([]string{}, template.Labels...)
+	occurrence.NextTime = nextTime
+	return placeholders.ApplyPlaceholders(&occurrence)
+}
+
+// Returns the next occurrence of each instance of the template
+func GetRecurringIssue(path string, lastTime time.Time, verbose bool) ([]*types.Metadata, error) {
+	instances, err := readRecurringIssues(path)
+	if err != nil {
+		return nil, err
+	}
+	occurrences := []*types.Metadata{}
+	for _, instance := range instances {
+		err = setAnchor(instance, lastTime, nil)
+		if err != nil {
+			return nil, err
+		}
+		nextTime, err := getNextExecutionTime(lastTime, instance, verbose)
+		if err != nil {
+			return nil, err
+		}
+		occurrence, err := getOccurrence(instance, nextTime)
+		if err != nil {
+			return nil, err
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
+
+func getCatchUpPolicy(data *types.Metadata) (string, error) {
+	catchUp := data.CatchUp
+	if catchUp == "" {
+		catchUp = gitlabUtils.GetCatchUp()
+	}
+	switch catchUp {
+	case constants.CatchUpAll, constants.CatchUpLatest, constants.CatchUpNone:
+		return catchUp, nil
+	case "":
+		return constants.CatchUpLatest, nil
+	}
+	return "", fmt.Errorf("unknown catchUp policy '%s' for %s, use one of %s, %s, or %s", catchUp, data.TemplateKey, constants.CatchUpAll, constants.CatchUpLatest, constants.CatchUpNone)
+}
+
+// Returns the occurrences since the last run that should be created according
+// to the catch up policy, and the first occurrence that is not due yet.
+// Without a last run, like before the first successful pipeline, only the
+// latest occurrence is created, and occurrences are caught up for at most
+// MaxCatchUpDays.
+func getDueOccurrences(lastTime time.Time, currentTime time.Time, data *types.Metadata, verbose bool) ([]time.Time, time.Time, error) {
+	catchUp, err := getCatchUpPolicy(data)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !lastTime.After(time.Unix(0, 0)) {
+		catchUp = constants.CatchUpLatest
+	}
+	earliestTime := currentTime.AddDate(0, 0, -constants.MaxCatchUpDays)
+	if lastTime.After(earliestTime) {
+		earliestTime = lastTime
+	}
+	wantedOccurrences := map[string]int{
+		constants.CatchUpAll:    constants.MaxCatchUpOccurrences,
+		constants.CatchUpLatest: 1,
+		constants.CatchUpNone:   1,
+	}[catchUp]
+	// Occurrences are searched back from the current time in growing periods,
+	// so frequent schedules don't walk through all occurrences since the last
+	// run
+	fromTime := earliestTime
+	for period := time.Hour; currentTime.Add(-period).After(earliestTime); period *= 2 {
+		dueTimes, _, err := walkOccurrences(currentTime.Add(-period), currentTime, data, false)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if len(dueTimes) >= wantedOccurrences {
+			fromTime = currentTime.Add(-period)
+			break
+		}
+	}
+	dueTimes, nextTime, err := walkOccurrences(fromTime, currentTime, data, verbose)
+	if err != nil {
+		return nil, nextTime, err
+	}
+	if len(dueTimes) > 1 && verbose {
+		log.Println("-- At least", len(dueTimes), "occurrences were due since the last run, catching up", catchUp)
+	}
+	switch catchUp {
+	case constants.CatchUpAll:
+		if len(dueTimes) > constants.MaxCatchUpOccurrences {
+			dueTimes = dueTimes[len(dueTimes)-constants.MaxCatchUpOccurrences:]
+		}
+	case constants.CatchUpLatest:
+		if len(dueTimes) > 1 {
+			dueTimes = dueTimes[len(dueTimes)-1:]
+		}
+	case constants.CatchUpNone:
+		// The most recent occurrence is the current one, since the next one is
+		// not due yet, only the missed ones before it are skipped
+		if len(dueTimes) > 1 {
+			if verbose {
+				log.Println("-- Skipping", len(dueTimes)-1, "missed occurrences")
+			}
+			dueTimes = dueTimes[len(dueTimes)-1:]
+		}
+	}
+	return dueTimes, nextTime, nil
+}
+
+// Returns the occurrences after fromTime that are due at the current time,
+// and the first occurrence that is not due yet
+func walkOccurrences(fromTime time.Time, currentTime time.Time, data *types.Metadata, verbose bool) ([]time.Time, time.Time, error) {
+	dueTimes := []time.Time{}
+	nextTime, err := getNextExecutionTime(fromTime, data, verbose)
+	if err != nil {
+		return nil, nextTime, err
+	}
+	for !nextTime.IsZero() && nextTime.Before(currentTime) {
+		dueTimes = append(dueTimes, nextTime)
+		followingTime, err := getNextExecutionTime(nextTime, data, verbose)
+		if err != nil {
+			return nil, nextTime, err
+		}
+		if followingTime.IsZero() {
+			nextTime = followingTime
+			break
+		}
+		if !followingTime.After(nextTime) {
+			break
+		}
+		nextTime = followingTime
+	}
+	return dueTimes, nextTime, nil
+}
+
+func GetTemplateKey(path string) string {
+	templateKey, err := filepath.Rel(gitlabUtils.GetRecurringIssuesPath(), path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(templateKey)
+}
+
+// Calls handleTemplate for each template, a failing template does not stop
+// the others
+func walkTemplates(handleTemplate func(path string, info os.FileInfo) error) []error {
+	failures := []error{}
+	err := filepath.Walk(gitlabUtils.GetRecurringIssuesPath(), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			failures = append(failures, err)
+			return nil
 		}
 		if filepath.Ext(path) != ".md" {
 			return nil
@@ -77,22 +359,167 @@ func processIssueFile(lastTime time.Time) filepath.WalkFunc {
 			// if recurranceExceptions.IsVacationUpcoming() {}
 			return nil
 		}
-		log.Println("- Checking", path)
-		verbose := true
-		data, err := GetRecurringIssue(path, lastTime, verbose)
+		err = handleTemplate(path, info)
+		if err != nil {
+			log.Println("-- Failed:", err)
+			failures = append(failures, fmt.Errorf("template %s: %w", GetTemplateKey(path), err))
+		}
+		return nil
+	})
+	if err != nil {
+		failures = append(failures, err)
+	}
+	return failures
+}
+
+func validateTemplate(path string) error {
+	instances, err := readRecurringIssues(path)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		err = validateInstance(instance)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateInstance(template *types.Metadata) error {
+	if template.Title == "" {
+		return errors.New("missing title")
+	}
+	err := validateRecurrence(template)
+	if err != nil {
+		return err
+	}
+	_, err = getCatchUpPolicy(template)
+	if err != nil {
+		return err
+	}
+	_, err = getPreviousOccurrencePolicy(template)
+	if err != nil {
+		return err
+	}
+	err = businessDays.ValidateShift(template.Shift)
+	if err != nil {
+		return err
+	}
+	if template.IssueType != "" {
+		err = gitlabUtils.ValidateIssueType(template.IssueType)
+		if err != nil {
+			return err
+		}
+	}
+	if template.Estimate != "" {
+		err = gitlabUtils.ValidateEstimate(template.Estimate)
 		if err != nil {
 			return err
 		}
-		if data.NextTime.Before(time.Now()) {
-			log.Println("--", info.Name(), "was due", data.NextTime.Format(time.RFC3339), "- creating new issue")
+	}
+	// Checks due date and placeholders for the next scheduled occurrence, since
+	// n-weekly recurrence needs the API
+	occurrence, err := getOccurrence(template, template.Schedule.Next(time.Now().In(template.Location)))
+	if err != nil {
+		return err
+	}
+	if occurrence.DueIn != "" {
+		_, err = gitlabUtils.GetIssueDueDate(occurrence)
+		if err != nil {
+			return err
+		}
+	}
+	return validateTasks(occurrence)
+}
 
-			err := gitlabUtils.CreateIssue(data)
+// Templates and instances that failed are retried from the time of their
+// failed run, a failing instance does not stop the others
+func processTemplate(path string, info os.FileInfo, lastTime time.Time, state *types.RunState) error {
+	templateKey := GetTemplateKey(path)
+	lastTime = stateStore.GetRetryTime(state, templateKey, lastTime)
+	instances, err := readRecurringIssues(path)
+	stateStore.SetResult(state, templateKey, lastTime, err)
+	if err != nil {
+		return err
+	}
+	var failure error
+	for _, instance := range instances {
+		name := getDisplayName(instance, info.Name())
+		instanceKey := getOccurrenceKey(instance)
+		instanceLastTime := stateStore.GetRetryTime(state, instanceKey, lastTime)
+		err = processInstance(instance, name, instanceLastTime, state)
+		stateStore.SetResult(state, instanceKey, instanceLastTime, err)
+		if err != nil && failure == nil {
+			failure = err
+		} else if err != nil {
+			log.Println("-- Failed:", name, err)
+		}
+	}
+	return failure
+}
+
+func processInstance(template *types.Metadata, name string, lastTime time.Time, state *types.RunState) error {
+	verbose := true
+	_, err := getPreviousOccurrencePolicy(template)
+	if err != nil {
+		return err
+	}
+	err = businessDays.ValidateShift(template.Shift)
+	if err != nil {
+		return err
+	}
+	err = setAnchor(template, lastTime, state)
+	if err != nil {
+		return err
+	}
+	dueTimes, nextTime, err := getDueOccurrences(lastTime, time.Now(), template, verbose)
+	if err != nil {
+		return err
+	}
+	// Issues created while catching up are the previous occurrence of the next
+	// one, which the search might not find yet
+	var previousIssue *gitlab.Issue
+	for _, dueTime := range dueTimes {
+		data, err := getOccurrence(template, dueTime)
+		if err != nil {
+			return err
+		}
+		log.Println("--", name, "was due", data.NextTime.Format(time.RFC3339), "- creating new issue")
+
+		if previousIssue == nil && usesPreviousOccurrence(data) {
+			previousIssue, err = gitlabUtils.GetPreviousOccurrenceIssue(data)
 			if err != nil {
 				return err
 			}
-		} else {
-			log.Println("--", info.Name(), "will be due", data.NextTime.Format(time.RFC3339))
 		}
+		if data.CarryOver && previousIssue != nil {
+			data.Description = addCarriedOverItems(data.Description, previousIssue.Description)
+		}
+		// The steps after creating the issue are repeated for an existing issue,
+		// since they might have failed when it was created
+		issue, existed, err := gitlabUtils.CreateIssue(data)
+		if err != nil {
+			return err
+		}
+		err = handlePreviousOccurrence(data, issue, previousIssue, existed)
+		if err != nil {
+			return err
+		}
+		err = createTasks(data, issue)
+		if err != nil {
+			return err
+		}
+		previousIssue = issue
+		if state.Occurrences == nil {
+			state.Occurrences = map[string]time.Time{}
+		}
+		state.Occurrences[getOccurrenceKey(data)] = data.NextTime
+	}
+	if nextTime.IsZero() {
+		log.Println("--", name, "has no further occurrences")
 		return nil
 	}
+	log.Println("--", name, "will be due", nextTime.Format(time.RFC3339))
+	return nil
 }
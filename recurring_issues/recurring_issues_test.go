@@ -1,9 +1,20 @@
 package recurringIssues
 
 import (
+	config "gitlab-issue-automation/config"
+	"gitlab-issue-automation/constants"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	occurrenceMarkers "gitlab-issue-automation/occurrence_markers"
 	types "gitlab-issue-automation/types"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+	"github.com/xanzy/go-gitlab"
 )
 
 func Test_parseMetadata(t *testing.T) {
@@ -96,6 +107,44 @@ duein: 24h
 				DueIn: "24h",
 			},
 		},
+		{
+			name: "Parses milestone and iteration",
+			args: args{contents: ([]byte)(`---
+milestone: current
+iteration: Sprint 12
+---
+`)},
+			want: &types.Metadata{
+				Milestone: "current",
+				Iteration: "Sprint 12",
+			},
+		},
+		{
+			name: "Parses weight, estimate, issue type and epic",
+			args: args{contents: ([]byte)(`---
+weight: 0
+estimate: 1h30m
+issueType: task
+epic: 12
+---
+`)},
+			want: &types.Metadata{
+				Weight:    gitlab.Ptr(0),
+				Estimate:  "1h30m",
+				IssueType: "task",
+				Epic:      "12",
+			},
+		},
+		{
+			name: "Parses catchUp",
+			args: args{contents: ([]byte)(`---
+catchUp: all
+---
+`)},
+			want: &types.Metadata{
+				CatchUp: "all",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -110,3 +159,1053 @@ duein: 24h
 		})
 	}
 }
+
+// Fixtures are given in UTC, so schedules without a timezone must not depend
+// on the timezone of the machine
+func TestMain(m *testing.M) {
+	utcConfig := config.Default()
+	utcConfig.Timezone = "UTC"
+	config.Set(utcConfig)
+	os.Exit(m.Run())
+}
+
+func writeTemplate(t *testing.T, name string, contents string) {
+	templatePath := filepath.Join(os.Getenv("CI_PROJECT_DIR"), constants.IssueTemplatePath, name)
+	err := os.MkdirAll(filepath.Dir(templatePath), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(templatePath, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Writes the templates to a new project directory and uses a memory tracker
+// until the test ends
+func setUpTracker(t *testing.T, templates map[string]string) *gitlabUtils.MemoryTracker {
+	t.Setenv("CI_PROJECT_DIR", t.TempDir())
+	for name, contents := range templates {
+		writeTemplate(t, name, contents)
+	}
+	tracker := gitlabUtils.NewMemoryTracker()
+	gitlabUtils.SetTracker(tracker)
+	t.Cleanup(func() { gitlabUtils.SetTracker(nil) })
+	return tracker
+}
+
+// Processes the templates as if the last run was at lastRunTime, setUp can
+// add users and issues to the tracker before
+func processTemplates(t *testing.T, templates map[string]string, lastRunTime time.Time, state *types.RunState, setUp func(tracker *gitlabUtils.MemoryTracker)) (*gitlabUtils.MemoryTracker, []error) {
+	tracker := setUpTracker(t, templates)
+	if setUp != nil {
+		setUp(tracker)
+	}
+	return tracker, ProcessIssueFiles(lastRunTime, state)
+}
+
+func getLatestOccurrence(hour int) time.Time {
+	currentTime := time.Now().In(time.UTC)
+	latestOccurrence := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), hour, 0, 0, 0, time.UTC)
+	if latestOccurrence.After(currentTime) {
+		latestOccurrence = latestOccurrence.AddDate(0, 0, -1)
+	}
+	return latestOccurrence
+}
+
+func TestProcessIssueFiles(t *testing.T) {
+	latestOccurrence := getLatestOccurrence(6)
+	tests := []struct {
+		name        string
+		template    string
+		lastRunTime time.Time
+		want        []*gitlab.Issue
+	}{
+		{
+			name: "Creates due issue",
+			template: `---
+title: Daily chore
+labels: [ "label1" ]
+duein: 24h
+crontab: "0 6 * * *"
+---
+Do the chore`,
+			lastRunTime: latestOccurrence.Add(-time.Hour),
+			want: []*gitlab.Issue{
+				{
+					Title:       "Daily chore",
+					Description: "Do the chore\n\n<!-- gitlab-issue-automation template=\"chore.md\" occurrence=\"" + latestOccurrence.Format(time.RFC3339) + "\" -->",
+					Labels:      gitlab.Labels{"label1", constants.RecurringLabel},
+					CreatedAt:   &latestOccurrence,
+					DueDate:     gitlab.Ptr(gitlab.ISOTime(latestOccurrence.AddDate(0, 0, 1))),
+				},
+			},
+		},
+		{
+			name: "Skips issue that is not due",
+			template: `---
+title: Daily chore
+crontab: "0 6 * * *"
+---
+Do the chore`,
+			lastRunTime: time.Now(),
+			want:        []*gitlab.Issue{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &types.RunState{}
+			tracker, _ := processTemplates(t, map[string]string{"chore.md": tt.template}, tt.lastRunTime.In(time.UTC), state, nil)
+			if len(tracker.Issues) != len(tt.want) {
+				t.Fatalf("ProcessIssueFiles() created %d issues, want %d", len(tracker.Issues), len(tt.want))
+			}
+			ProcessIssueFiles(tt.lastRunTime.In(time.UTC), state)
+			if len(tracker.Issues) != len(tt.want) {
+				t.Fatalf("ProcessIssueFiles() created %d issues when run again, want %d", len(tracker.Issues), len(tt.want))
+			}
+			if len(tt.want) > 0 && !state.Occurrences["chore.md"].Equal(*tt.want[len(tt.want)-1].CreatedAt) {
+				t.Errorf("ProcessIssueFiles() recorded occurrences %v", state.Occurrences)
+			}
+			for index, want := range tt.want {
+				got := tracker.Issues[index]
+				if got.Title != want.Title || got.Description != want.Description || !reflect.DeepEqual(got.Labels, want.Labels) {
+					t.Errorf("ProcessIssueFiles() = %v, want %v", got, want)
+				}
+				if !got.CreatedAt.Equal(*want.CreatedAt) || got.DueDate.String() != want.DueDate.String() {
+					t.Errorf("ProcessIssueFiles() created at %v due %v, want %v due %v", got.CreatedAt, got.DueDate, want.CreatedAt, want.DueDate)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessIssueFilesCatchUp(t *testing.T) {
+	lastRunTime := time.Now().In(time.UTC).AddDate(0, 0, -3)
+	latestOccurrence := getLatestOccurrence(6)
+	tests := []struct {
+		name            string
+		catchUp         string
+		globalCatchUp   string
+		lastRunTime     time.Time
+		wantOccurrences []time.Time
+	}{
+		{
+			name:            "Creates all missed occurrences",
+			catchUp:         "all",
+			wantOccurrences: []time.Time{latestOccurrence.AddDate(0, 0, -2), latestOccurrence.AddDate(0, 0, -1), latestOccurrence},
+		},
+		{
+			name:            "Creates latest occurrence",
+			catchUp:         "latest",
+			wantOccurrences: []time.Time{latestOccurrence},
+		},
+		{
+			name:            "Skips missed occurrences",
+			catchUp:         "none",
+			wantOccurrences: []time.Time{latestOccurrence},
+		},
+		{
+			name:            "Creates current occurrence after one missed run",
+			catchUp:         "none",
+			lastRunTime:     latestOccurrence.AddDate(0, 0, -1).Add(-time.Hour),
+			wantOccurrences: []time.Time{latestOccurrence},
+		},
+		{
+			name:            "Uses global policy",
+			globalCatchUp:   "all",
+			wantOccurrences: []time.Time{latestOccurrence.AddDate(0, 0, -2), latestOccurrence.AddDate(0, 0, -1), latestOccurrence},
+		},
+		{
+			name:            "Prefers template policy",
+			catchUp:         "none",
+			globalCatchUp:   "all",
+			wantOccurrences: []time.Time{latestOccurrence},
+		},
+		{
+			name:            "Creates latest occurrence by default",
+			wantOccurrences: []time.Time{latestOccurrence},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CATCH_UP", tt.globalCatchUp)
+			if tt.lastRunTime.IsZero() {
+				tt.lastRunTime = lastRunTime
+			}
+			tracker, _ := processTemplates(t, map[string]string{"chore.md": `---
+title: Daily chore
+crontab: "0 6 * * *"
+catchUp: "` + tt.catchUp + `"
+---
+Do the chore`}, tt.lastRunTime, &types.RunState{}, nil)
+			// Occurrences are in the timezone of the template
+			gotOccurrences := []time.Time{}
+			for _, issue := range tracker.Issues {
+				gotOccurrences = append(gotOccurrences, issue.CreatedAt.In(time.UTC))
+			}
+			if !reflect.DeepEqual(gotOccurrences, tt.wantOccurrences) {
+				t.Errorf("ProcessIssueFiles() created occurrences %v, want %v", gotOccurrences, tt.wantOccurrences)
+			}
+		})
+	}
+}
+
+func TestProcessIssueFilesCatchUpExceptions(t *testing.T) {
+	latestOccurrence := getLatestOccurrence(6)
+	exceptionStart := latestOccurrence.AddDate(0, 0, -3).Format("2006-01-02")
+	exceptionEnd := latestOccurrence.AddDate(0, 0, -2).Format("2006-01-02")
+	tracker, failures := processTemplates(t, map[string]string{
+		"chore.md": `---
+title: Daily chore
+id: chore
+crontab: "0 6 * * *"
+catchUp: all
+---
+Do the chore`,
+		// The exception has already ended, but missed occurrences in it are
+		// still skipped
+		"recurrance_exceptions.yml": `definitions:
+  - id: vacation
+    start: "` + exceptionStart + `"
+    end: "` + exceptionEnd + `"
+rules:
+  - issue: chore
+    exceptions: ["vacation"]
+`,
+	}, latestOccurrence.AddDate(0, 0, -4).Add(-time.Hour), &types.RunState{}, nil)
+	gotOccurrences := []time.Time{}
+	for _, issue := range tracker.Issues {
+		gotOccurrences = append(gotOccurrences, issue.CreatedAt.In(time.UTC))
+	}
+	wantOccurrences := []time.Time{latestOccurrence.AddDate(0, 0, -4), latestOccurrence.AddDate(0, 0, -1), latestOccurrence}
+	if len(failures) > 0 || !reflect.DeepEqual(gotOccurrences, wantOccurrences) {
+		t.Errorf("ProcessIssueFiles() = %v, created occurrences %v, want %v", failures, gotOccurrences, wantOccurrences)
+	}
+}
+
+func Test_getDueOccurrences(t *testing.T) {
+	currentTime := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		crontab   string
+		lastTime  time.Time
+		wantCount int
+		wantLast  time.Time
+	}{
+		{
+			name:      "Creates latest occurrence without last run",
+			crontab:   "0 6 * * *",
+			lastTime:  time.Unix(0, 0),
+			wantCount: 1,
+			wantLast:  time.Date(2026, 3, 4, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "Catches up latest occurrences of frequent schedule",
+			crontab:   "* * * * *",
+			lastTime:  currentTime.AddDate(-3, 0, 0),
+			wantCount: constants.MaxCatchUpOccurrences,
+			wantLast:  time.Date(2026, 3, 4, 10, 29, 0, 0, time.UTC),
+		},
+		{
+			name:      "Catches up at most MaxCatchUpDays",
+			crontab:   "0 6 1 1 *",
+			lastTime:  currentTime.AddDate(-3, 0, 0),
+			wantCount: 1,
+			wantLast:  time.Date(2026, 1, 1, 6, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &types.Metadata{Crontab: tt.crontab, CatchUp: constants.CatchUpAll, Schedule: cronexpr.MustParse(tt.crontab)}
+			dueTimes, _, err := getDueOccurrences(tt.lastTime, currentTime, data, false)
+			if err != nil || len(dueTimes) != tt.wantCount || !dueTimes[len(dueTimes)-1].Equal(tt.wantLast) {
+				t.Errorf("getDueOccurrences() = %d occurrences %v, %v, want %d until %v", len(dueTimes), dueTimes, err, tt.wantCount, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestProcessIssueFilesIsolatesFailures(t *testing.T) {
+	latestOccurrence := getLatestOccurrence(6)
+	state := &types.RunState{}
+	tracker, failures := processTemplates(t, map[string]string{
+		"broken.md": `---
+title: Broken chore
+duein: soon
+crontab: "0 6 * * *"
+---
+Do the chore`,
+		"chore.md": `---
+title: Daily chore
+crontab: "0 6 * * *"
+---
+Do the chore`,
+	}, latestOccurrence.Add(-time.Hour), state, nil)
+	if len(failures) != 1 || !strings.Contains(failures[0].Error(), "broken.md") {
+		t.Errorf("ProcessIssueFiles() failures = %v, want failure for broken.md", failures)
+	}
+	if len(tracker.Issues) != 1 || tracker.Issues[0].Title != "Daily chore" {
+		t.Errorf("ProcessIssueFiles() created %v, want only Daily chore", tracker.Issues)
+	}
+	if _, exists := state.Failed["chore.md"]; exists || !state.Failed["broken.md"].Equal(latestOccurrence.Add(-time.Hour)) {
+		t.Errorf("ProcessIssueFiles() failed entries = %v, want only broken.md", state.Failed)
+	}
+
+	// The next run starts after the occurrence, but the fixed template is
+	// retried from its failed run
+	writeTemplate(t, "broken.md", `---
+title: Broken chore
+duein: 24h
+crontab: "0 6 * * *"
+---
+Do the chore`)
+	failures = ProcessIssueFiles(latestOccurrence.Add(time.Hour), state)
+	if len(failures) != 0 || len(tracker.Issues) != 2 || tracker.Issues[1].Title != "Broken chore" {
+		t.Errorf("ProcessIssueFiles() = %v, created %v, want Broken chore to be retried", failures, tracker.Issues)
+	}
+	if len(state.Failed) != 0 {
+		t.Errorf("ProcessIssueFiles() failed entries = %v, want none", state.Failed)
+	}
+}
+
+func TestValidateIssueFiles(t *testing.T) {
+	t.Setenv("CI_PROJECT_DIR", t.TempDir())
+	writeTemplate(t, "valid.md", `---
+title: Daily chore
+duein: 24h
+crontab: "0 6 * * *"
+---
+Do the chore`)
+	writeTemplate(t, "invalid-crontab.md", `---
+title: Daily chore
+crontab: "0 6 * *"
+---
+Do the chore`)
+	writeTemplate(t, "invalid-duein.md", `---
+title: Daily chore
+duein: soon
+crontab: "0 6 * * *"
+---
+Do the chore`)
+	writeTemplate(t, "invalid-catch-up.md", `---
+title: Daily chore
+crontab: "0 6 * * *"
+catchUp: sometimes
+---
+Do the chore`)
+	writeTemplate(t, "invalid-issue-type.md", `---
+title: Daily chore
+crontab: "0 6 * * *"
+issueType: bug
+---
+Do the chore`)
+	writeTemplate(t, "invalid-estimate.md", `---
+title: Daily chore
+crontab: "0 6 * * *"
+estimate: a while
+---
+Do the chore`)
+	writeTemplate(t, "invalid-previous-occurrence.md", `---
+title: Daily chore
+crontab: "0 6 * * *"
+previousOccurrence: delete
+---
+Do the chore`)
+
+	writeTemplate(t, "invalid-task.md", `---
+title: Daily chore
+crontab: "0 6 * * *"
+tasks:
+  - assignee: alice
+---
+Do the chore`)
+
+	writeTemplate(t, "invalid-instances.md", `---
+title: Daily chore
+crontab: "0 6 * * *"
+instances: [{id: alice}, {id: alice}]
+---
+Do the chore`)
+
+	writeTemplate(t, "invalid-rrule.md", `---
+title: Daily chore
+crontab: "0 6 * * *"
+rrule: "DTSTART:20260105T090000\nRRULE:FREQ=DAILY"
+---
+Do the chore`)
+
+	writeTemplate(t, "invalid-shift.md", `---
+title: Daily chore
+crontab: "0 6 * * *"
+shift: later
+---
+Do the chore`)
+	writeTemplate(t, "invalid-anchor-date.md", `---
+title: Biweekly chore
+crontab: "0 6 * * 1"
+weeklyRecurrence: 2
+anchorDate: 03/11/2024
+---
+Do the chore`)
+	writeTemplate(t, "invalid-recurrence.md", `---
+title: Quarterly chore
+crontab: "0 6 1 * *"
+weeklyRecurrence: 2
+monthlyRecurrence: 3
+---
+Do the chore`)
+
+	failures := ValidateIssueFiles()
+	if len(failures) != 12 {
+		t.Fatalf("ValidateIssueFiles() = %v, want 12 failures", failures)
+	}
+	for _, failure := range failures {
+		if !strings.Contains(failure.Error(), "invalid-") {
+			t.Errorf("ValidateIssueFiles() failed for valid template: %v", failure)
+		}
+	}
+}
+
+func TestGetNextOccurrences(t *testing.T) {
+	t.Setenv("CI_PROJECT_DIR", t.TempDir())
+	writeTemplate(t, "daily.md", `---
+title: Daily chore
+crontab: "0 6 * * *"
+---
+Do the chore`)
+	writeTemplate(t, "hourly.md", `---
+title: Hourly chore
+crontab: "0 * * * *"
+---
+Do the chore`)
+	fromTime := time.Date(2024, 3, 4, 6, 30, 0, 0, time.UTC)
+
+	occurrences, failures := GetNextOccurrences(fromTime, 2, nil)
+	if len(failures) > 0 {
+		t.Fatal(failures)
+	}
+	want := []string{"hourly.md 2024-03-04T07:00:00Z", "hourly.md 2024-03-04T08:00:00Z", "daily.md 2024-03-05T06:00:00Z", "daily.md 2024-03-06T06:00:00Z"}
+	got := []string{}
+	for _, occurrence := range occurrences {
+		got = append(got, occurrence.TemplateKey+" "+occurrence.NextTime.Format(time.RFC3339))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNextOccurrences() = %v, want %v", got, want)
+	}
+}
+
+func TestGetNextOccurrencesTimezone(t *testing.T) {
+	t.Setenv("CI_PROJECT_DIR", t.TempDir())
+	writeTemplate(t, "weekly.md", `---
+title: Weekly planning
+crontab: "0 0 * * MON"
+timezone: Europe/Berlin
+duein: 1h
+---
+Plan the week`)
+	fromTime := time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)
+
+	occurrences, failures := GetNextOccurrences(fromTime, 2, nil)
+	if len(failures) > 0 {
+		t.Fatal(failures)
+	}
+	// Midnight in Berlin is on the previous day in UTC, and the change to
+	// summer time on March 31 shifts the occurrence by an hour
+	want := []string{"2024-03-24T23:00:00Z 2024-03-25", "2024-03-31T22:00:00Z 2024-04-01"}
+	got := []string{}
+	for _, occurrence := range occurrences {
+		dueDate, err := gitlabUtils.GetIssueDueDate(occurrence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, occurrence.NextTime.In(time.UTC).Format(time.RFC3339)+" "+gitlab.ISOTime(dueDate).String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNextOccurrences() = %v, want %v", got, want)
+	}
+}
+
+func TestGetNextOccurrencesRRule(t *testing.T) {
+	t.Setenv("CI_PROJECT_DIR", t.TempDir())
+	writeTemplate(t, "review.md", `---
+title: Weekly review
+id: weekly-review
+timezone: UTC
+rrule: |
+  DTSTART:20260105T090000
+  RRULE:FREQ=WEEKLY;BYDAY=MO
+---
+Review the week`)
+	fromTime := time.Now().In(time.UTC)
+	nextMonday := time.Date(fromTime.Year(), fromTime.Month(), fromTime.Day()+1, 9, 0, 0, 0, time.UTC)
+	for nextMonday.Weekday() != time.Monday {
+		nextMonday = nextMonday.AddDate(0, 0, 1)
+	}
+	writeTemplate(t, "recurrance_exceptions.yml", `definitions:
+  - id: offsite
+    start: "`+nextMonday.Format("2006-01-02")+`"
+    end: "`+nextMonday.Format("2006-01-02")+`"
+rules:
+  - issue: weekly-review
+    exceptions: ["offsite"]
+`)
+
+	occurrences, failures := GetNextOccurrences(fromTime, 2, nil)
+	if len(failures) > 0 {
+		t.Fatal(failures)
+	}
+	want := []string{nextMonday.AddDate(0, 0, 7).Format(time.RFC3339), nextMonday.AddDate(0, 0, 14).Format(time.RFC3339)}
+	got := []string{}
+	for _, occurrence := range occurrences {
+		got = append(got, occurrence.NextTime.Format(time.RFC3339))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNextOccurrences() = %v, want %v", got, want)
+	}
+}
+
+func TestGetNextOccurrencesCalendarExceptions(t *testing.T) {
+	t.Setenv("CI_PROJECT_DIR", t.TempDir())
+	writeTemplate(t, "daily.md", `---
+title: Daily chore
+id: daily-chore
+timezone: UTC
+crontab: "0 9 * * *"
+---
+Do the chore`)
+	now := time.Now().In(time.UTC)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	getDate := func(days int) string {
+		return tomorrow.AddDate(0, 0, days).Format("20060102")
+	}
+	writeTemplate(t, "vacation.ics", "BEGIN:VCALENDAR\r\n"+
+		"BEGIN:VEVENT\r\nSUMMARY:Vacation\r\nDTSTART;VALUE=DATE:"+getDate(0)+"\r\nDTEND;VALUE=DATE:"+getDate(2)+"\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nSUMMARY:Vacation\r\nDTSTART;VALUE=DATE:"+getDate(3)+"\r\nEND:VEVENT\r\n"+
+		"END:VCALENDAR\r\n")
+	writeTemplate(t, "recurrance_exceptions.yml", `definitions:
+  - id: vacation
+    calendar: vacation.ics
+rules:
+  - issue: daily-chore
+    exceptions: ["vacation-*"]
+`)
+
+	occurrences, failures := GetNextOccurrences(tomorrow, 3, nil)
+	if len(failures) > 0 {
+		t.Fatal(failures)
+	}
+	want := []string{}
+	for _, days := range []int{2, 4, 5} {
+		want = append(want, tomorrow.AddDate(0, 0, days).Add(9*time.Hour).Format(time.RFC3339))
+	}
+	got := []string{}
+	for _, occurrence := range occurrences {
+		got = append(got, occurrence.NextTime.Format(time.RFC3339))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNextOccurrences() = %v, want %v", got, want)
+	}
+}
+
+func TestGetNextOccurrencesWeeklyRecurrence(t *testing.T) {
+	// Mondays, the first from time is in the week of March 4
+	previousOccurrence := time.Date(2024, 2, 26, 9, 0, 0, 0, time.UTC)
+	fromTime := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		anchorDate string
+		state      *types.RunState
+		marker     bool
+		want       []string
+	}{
+		{
+			name:       "Counts weeks from anchorDate",
+			anchorDate: "anchorDate: 2024-03-11",
+			want:       []string{"2024-03-11", "2024-03-25", "2024-04-08"},
+		},
+		{
+			name:       "Counts weeks from anchorDate in the past",
+			anchorDate: "anchorDate: 2023-12-25",
+			want:       []string{"2024-03-18", "2024-04-01", "2024-04-15"},
+		},
+		{
+			name:  "Falls back to last occurrence in state",
+			state: &types.RunState{Occurrences: map[string]time.Time{"review.md": previousOccurrence.AddDate(0, 0, 7)}},
+			want:  []string{"2024-03-18", "2024-04-01", "2024-04-15"},
+		},
+		{
+			name:   "Falls back to occurrence markers",
+			marker: true,
+			want:   []string{"2024-03-11", "2024-03-25", "2024-04-08"},
+		},
+		{
+			name: "Starts new templates with their first occurrence",
+			want: []string{"2024-03-11", "2024-03-25", "2024-04-08"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := setUpTracker(t, map[string]string{"review.md": `---
+title: Review of {last_month}
+timezone: UTC
+crontab: "0 9 * * 1"
+weeklyRecurrence: 2
+` + tt.anchorDate + `
+---
+Review the last two weeks`})
+			// Issues with the same title but of another template are ignored
+			tracker.AddIssue(&gitlab.Issue{Title: "Review of February", CreatedAt: &fromTime})
+			if tt.marker {
+				marker := occurrenceMarkers.GetMarker(&types.Metadata{TemplateKey: "review.md", NextTime: previousOccurrence})
+				tracker.AddIssue(&gitlab.Issue{Title: "Review of January", Description: marker, CreatedAt: &previousOccurrence})
+			}
+
+			occurrences, failures := GetNextOccurrences(fromTime, 3, tt.state)
+			if len(failures) > 0 {
+				t.Fatal(failures)
+			}
+			got := []string{}
+			for _, occurrence := range occurrences {
+				got = append(got, occurrence.NextTime.Format("2006-01-02"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetNextOccurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNextOccurrencesShift(t *testing.T) {
+	t.Setenv("CI_PROJECT_DIR", t.TempDir())
+	writeTemplate(t, "review.md", `---
+title: Weekly review
+crontab: "0 9 * * SAT"
+timezone: UTC
+shift: next
+duein: 2bd
+---
+Review the week`)
+	writeTemplate(t, "holidays.yml", `["2026-01-05"]`)
+	fromTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	occurrences, failures := GetNextOccurrences(fromTime, 2, nil)
+	if len(failures) > 0 {
+		t.Fatal(failures)
+	}
+	// The first Saturday is shifted over the holiday on Monday to Tuesday
+	want := []string{"2026-01-06T09:00:00Z 2026-01-08", "2026-01-12T09:00:00Z 2026-01-14"}
+	got := []string{}
+	for _, occurrence := range occurrences {
+		dueDate, err := gitlabUtils.GetIssueDueDate(occurrence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, occurrence.NextTime.Format(time.RFC3339)+" "+gitlab.ISOTime(dueDate).String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNextOccurrences() = %v, want %v", got, want)
+	}
+}
+
+func TestGetNextOccurrencesShiftPrevious(t *testing.T) {
+	t.Setenv("CI_PROJECT_DIR", t.TempDir())
+	writeTemplate(t, "chore.md", `---
+title: Daily chore
+crontab: "0 9 * * *"
+timezone: UTC
+shift: previous
+---
+Do the chore`)
+	fromTime := time.Date(2026, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	occurrences, failures := GetNextOccurrences(fromTime, 3, nil)
+	if len(failures) > 0 {
+		t.Fatal(failures)
+	}
+	// The weekend is merged into Friday
+	want := []string{"2026-01-09T09:00:00Z", "2026-01-12T09:00:00Z", "2026-01-13T09:00:00Z"}
+	got := []string{}
+	for _, occurrence := range occurrences {
+		got = append(got, occurrence.NextTime.Format(time.RFC3339))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNextOccurrences() = %v, want %v", got, want)
+	}
+}
+
+func TestProcessIssueFilesAssignees(t *testing.T) {
+	latestOccurrence := getLatestOccurrence(6)
+	tests := []struct {
+		name          string
+		assignees     string
+		wantAssignees []int
+		wantErr       bool
+	}{
+		{name: "Assigns users", assignees: `["alice", "@bob"]`, wantAssignees: []int{7, 8}},
+		{name: "Fails for unknown users", assignees: `["alice", "mallory"]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, failures := processTemplates(t, map[string]string{"chore.md": `---
+title: Daily chore
+assignees: ` + tt.assignees + `
+crontab: "0 6 * * *"
+---
+Do the chore`}, latestOccurrence.Add(-time.Hour), &types.RunState{}, func(tracker *gitlabUtils.MemoryTracker) {
+				tracker.Users = map[string]int{"alice": 7, "bob": 8}
+			})
+			if tt.wantErr {
+				if len(failures) != 1 || !strings.Contains(failures[0].Error(), "unknown assignee 'mallory'") || len(tracker.Issues) != 0 {
+					t.Errorf("ProcessIssueFiles() = %v, created %d issues, want unknown assignee", failures, len(tracker.Issues))
+				}
+				return
+			}
+			if len(failures) > 0 || len(tracker.Issues) != 1 {
+				t.Fatalf("ProcessIssueFiles() = %v, created %d issues, want 1", failures, len(tracker.Issues))
+			}
+			got := []int{}
+			for _, assignee := range tracker.Issues[0].Assignees {
+				got = append(got, assignee.ID)
+			}
+			if !reflect.DeepEqual(got, tt.wantAssignees) {
+				t.Errorf("ProcessIssueFiles() assignees = %v, want %v", got, tt.wantAssignees)
+			}
+		})
+	}
+}
+
+func TestProcessIssueFilesPreviousOccurrence(t *testing.T) {
+	latestOccurrence := getLatestOccurrence(6)
+	tests := []struct {
+		name              string
+		settings          string
+		wantLinks         []gitlabUtils.MemoryIssueLink
+		wantNotes         map[int][]string
+		wantPreviousState string
+		wantMissed        bool
+	}{
+		{
+			name:      "Links previous occurrence",
+			settings:  "linkPrevious: true",
+			wantLinks: []gitlabUtils.MemoryIssueLink{{IssueId: 4, TargetIssueId: 2, LinkType: "relates_to"}},
+			wantNotes: map[int][]string{},
+		},
+		{
+			name:      "Adds note to previous occurrence",
+			settings:  "notePrevious: true",
+			wantLinks: []gitlabUtils.MemoryIssueLink{{IssueId: 4, TargetIssueId: 2, LinkType: "relates_to"}},
+			wantNotes: map[int][]string{2: {"Next occurrence: #4"}},
+		},
+		{
+			name:              "Closes previous occurrence",
+			settings:          "previousOccurrence: close",
+			wantNotes:         map[int][]string{2: {"Closed automatically because the next occurrence #4 was created."}},
+			wantPreviousState: "closed",
+		},
+		{
+			name:       "Labels previous occurrence",
+			settings:   "previousOccurrence: label",
+			wantNotes:  map[int][]string{},
+			wantMissed: true,
+		},
+		{
+			name:      "Keeps previous occurrence by default",
+			wantNotes: map[int][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, failures := processTemplates(t, map[string]string{"chore.md": `---
+title: Daily chore
+crontab: "0 6 * * *"
+` + tt.settings + `
+---
+Do the chore`}, latestOccurrence.Add(-time.Hour), &types.RunState{}, func(tracker *gitlabUtils.MemoryTracker) {
+				for _, previous := range []types.Metadata{
+					{TemplateKey: "chore.md", NextTime: latestOccurrence.AddDate(0, 0, -2)},
+					{TemplateKey: "chore.md", NextTime: latestOccurrence.AddDate(0, 0, -1)},
+					{TemplateKey: "other.md", NextTime: latestOccurrence.AddDate(0, 0, -1)},
+				} {
+					tracker.AddIssue(&gitlab.Issue{Title: "Previous", Description: occurrenceMarkers.AddMarker("", &previous)})
+				}
+			})
+			if len(failures) > 0 || len(tracker.Issues) != 4 {
+				t.Fatalf("ProcessIssueFiles() = %v, created %d issues, want 1", failures, len(tracker.Issues)-3)
+			}
+			if !reflect.DeepEqual(tracker.IssueLinks, tt.wantLinks) || !reflect.DeepEqual(tracker.Notes, tt.wantNotes) {
+				t.Errorf("ProcessIssueFiles() links = %v, notes = %v, want %v, %v", tracker.IssueLinks, tracker.Notes, tt.wantLinks, tt.wantNotes)
+			}
+			if tt.wantPreviousState == "" {
+				tt.wantPreviousState = "opened"
+			}
+			previousIssue := tracker.GetIssue(2)
+			gotMissed := reflect.DeepEqual(previousIssue.Labels, gitlab.Labels{constants.MissedLabel})
+			if previousIssue.State != tt.wantPreviousState || gotMissed != tt.wantMissed {
+				t.Errorf("ProcessIssueFiles() previous occurrence %v with labels %v, want %v, missed %v", previousIssue.State, previousIssue.Labels, tt.wantPreviousState, tt.wantMissed)
+			}
+			if tracker.GetIssue(1).State != "opened" || len(tracker.GetIssue(1).Labels) != 0 {
+				t.Errorf("ProcessIssueFiles() changed older occurrence %v", tracker.GetIssue(1))
+			}
+		})
+	}
+}
+
+func Test_addCarriedOverItems(t *testing.T) {
+	tests := []struct {
+		name                string
+		description         string
+		previousDescription string
+		want                string
+	}{
+		{
+			name:                "Appends unchecked items that are not in the template",
+			description:         "Review:\n\n* [ ] Inbox\n* [ ] Calendar\n",
+			previousDescription: "Review:\n\n* [x] Inbox\n* [ ] Calendar\n* [ ] Call Bob\n- [ ] Book room\n* [X] Send report",
+			want:                "Review:\n\n* [ ] Inbox\n* [ ] Calendar\n\n## Carried over\n\n* [ ] Call Bob\n* [ ] Book room\n",
+		},
+		{
+			name:                "Carries over items again",
+			description:         "* [ ] Inbox",
+			previousDescription: "* [ ] Inbox\n\n## Carried over\n\n* [ ] Call Bob\n* [ ] Call Bob\n",
+			want:                "* [ ] Inbox\n\n## Carried over\n\n* [ ] Call Bob\n",
+		},
+		{
+			name:                "Keeps description without unchecked items",
+			description:         "* [ ] Inbox",
+			previousDescription: "* [x] Inbox\n* [x] Call Bob",
+			want:                "* [ ] Inbox",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addCarriedOverItems(tt.description, tt.previousDescription); got != tt.want {
+				t.Errorf("addCarriedOverItems() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessIssueFilesCarryOver(t *testing.T) {
+	latestOccurrence := getLatestOccurrence(6)
+	tracker, failures := processTemplates(t, map[string]string{"chore.md": `---
+title: Daily chore
+crontab: "0 6 * * *"
+carryOver: true
+---
+* [ ] Inbox`}, latestOccurrence.Add(-time.Hour), &types.RunState{}, func(tracker *gitlabUtils.MemoryTracker) {
+		previous := types.Metadata{TemplateKey: "chore.md", NextTime: latestOccurrence.AddDate(0, 0, -1)}
+		tracker.AddIssue(&gitlab.Issue{Title: "Daily chore", Description: occurrenceMarkers.AddMarker("* [ ] Inbox\n* [ ] Call Bob", &previous)})
+	})
+	if len(failures) > 0 || len(tracker.Issues) != 2 {
+		t.Fatalf("ProcessIssueFiles() = %v, created %d issues, want 1", failures, len(tracker.Issues)-1)
+	}
+	want := "* [ ] Inbox\n\n## Carried over\n\n* [ ] Call Bob\n"
+	if !strings.HasPrefix(tracker.Issues[1].Description, want) {
+		t.Errorf("ProcessIssueFiles() description = %q, want %q", tracker.Issues[1].Description, want)
+	}
+}
+
+func TestProcessIssueFilesTasks(t *testing.T) {
+	latestOccurrence := getLatestOccurrence(6)
+	tracker, failures := processTemplates(t, map[string]string{"chore.md": `---
+title: Monthly report
+crontab: "0 6 * * *"
+tasks:
+  - title: Collect numbers for {last_month}
+    assignee: alice
+    labels: ["finance"]
+    duein: 48h
+  - title: Write summary
+---
+Write the report`}, latestOccurrence.Add(-time.Hour), &types.RunState{}, func(tracker *gitlabUtils.MemoryTracker) {
+		tracker.Users = map[string]int{"alice": 7}
+	})
+	if len(failures) > 0 || len(tracker.Issues) != 3 {
+		t.Fatalf("ProcessIssueFiles() = %v, created %d issues, want 3", failures, len(tracker.Issues))
+	}
+	lastMonth := time.Date(latestOccurrence.Year(), latestOccurrence.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0).Month().String()
+	task := tracker.Issues[1]
+	if task.Title != "Collect numbers for "+lastMonth || len(task.Assignees) != 1 || task.Assignees[0].ID != 7 {
+		t.Errorf("ProcessIssueFiles() task = %v with assignees %v", task.Title, task.Assignees)
+	}
+	wantDueDate := latestOccurrence.AddDate(0, 0, 2).Format("2006-01-02")
+	if !reflect.DeepEqual(task.Labels, gitlab.Labels{"finance", constants.RecurringLabel}) || task.DueDate.String() != wantDueDate {
+		t.Errorf("ProcessIssueFiles() task labels %v due %v, want finance due %v", task.Labels, task.DueDate, wantDueDate)
+	}
+	if !strings.HasPrefix(task.Description, "Task of #1") || tracker.Issues[2].Title != "Write summary" {
+		t.Errorf("ProcessIssueFiles() tasks = %v, %v", task, tracker.Issues[2])
+	}
+	wantLinks := []gitlabUtils.MemoryIssueLink{{IssueId: 2, TargetIssueId: 1, LinkType: "relates_to"}, {IssueId: 3, TargetIssueId: 1, LinkType: "relates_to"}}
+	if !reflect.DeepEqual(tracker.IssueLinks, wantLinks) {
+		t.Errorf("ProcessIssueFiles() links = %v, want %v", tracker.IssueLinks, wantLinks)
+	}
+
+	// Tasks are not created or linked again for the same occurrence
+	failures = ProcessIssueFiles(latestOccurrence.Add(-time.Hour), &types.RunState{})
+	if len(failures) > 0 || len(tracker.Issues) != 3 || !reflect.DeepEqual(tracker.IssueLinks, wantLinks) {
+		t.Errorf("ProcessIssueFiles() = %v, created %d issues and links %v on second run, want 3", failures, len(tracker.Issues), tracker.IssueLinks)
+	}
+}
+
+func TestProcessIssueFilesRetriesTasksOfExistingIssue(t *testing.T) {
+	latestOccurrence := getLatestOccurrence(6)
+	previousOccurrence := latestOccurrence.AddDate(0, 0, -1)
+	var previousIssue *gitlab.Issue
+	tracker, failures := processTemplates(t, map[string]string{"chore.md": `---
+title: Monthly report
+crontab: "0 6 * * *"
+notePrevious: true
+tasks:
+  - title: Collect numbers
+    assignee: alice
+---
+Write the report`}, latestOccurrence.Add(-time.Hour), &types.RunState{}, func(tracker *gitlabUtils.MemoryTracker) {
+		previousIssue = tracker.AddIssue(&gitlab.Issue{
+			Title:       "Monthly report",
+			State:       "opened",
+			Description: occurrenceMarkers.GetMarker(&types.Metadata{TemplateKey: "chore.md", NextTime: previousOccurrence}),
+			CreatedAt:   &previousOccurrence,
+		})
+	})
+	if len(failures) != 1 || len(tracker.Issues) != 2 {
+		t.Fatalf("ProcessIssueFiles() = %v, created %d issues, want failing task", failures, len(tracker.Issues))
+	}
+
+	// The existing issue is kept, and only its missing task is created
+	tracker.Users = map[string]int{"alice": 7}
+	failures = ProcessIssueFiles(latestOccurrence.Add(-time.Hour), &types.RunState{})
+	if len(failures) > 0 || len(tracker.Issues) != 3 || tracker.Issues[2].Title != "Collect numbers" {
+		t.Fatalf("ProcessIssueFiles() = %v, created %v, want the task", failures, tracker.Issues)
+	}
+	wantLinks := []gitlabUtils.MemoryIssueLink{{IssueId: 2, TargetIssueId: previousIssue.IID, LinkType: "relates_to"}, {IssueId: 3, TargetIssueId: 2, LinkType: "relates_to"}}
+	if !reflect.DeepEqual(tracker.IssueLinks, wantLinks) || len(tracker.Notes[previousIssue.IID]) != 1 {
+		t.Errorf("ProcessIssueFiles() links = %v, notes = %v, want each once", tracker.IssueLinks, tracker.Notes)
+	}
+}
+
+func TestProcessIssueFilesInstances(t *testing.T) {
+	latestOccurrence := getLatestOccurrence(6)
+	today := latestOccurrence.Format("2006-01-02")
+	state := &types.RunState{}
+	tracker, failures := processTemplates(t, map[string]string{
+		// Instances of defaults and template are merged like other lists
+		"_defaults.yml": `instances: [{id: alice, name: Alice}]`,
+		"report.md": `---
+title: Report of {name}
+id: report
+assignees: ["{id}"]
+crontab: "0 6 * * *"
+instances:
+  - id: bob
+    name: Bob
+  - id: carol
+    name: Carol
+---
+Hi {name}, please write the report`,
+		"recurrance_exceptions.yml": `definitions:
+  - id: vacation-carol
+    start: "` + today + `"
+    end: "` + today + `"
+rules:
+  - issue: report-carol
+    exceptions: ["vacation-carol"]
+`,
+	}, latestOccurrence.Add(-time.Hour), state, func(tracker *gitlabUtils.MemoryTracker) {
+		tracker.Users = map[string]int{"alice": 7, "bob": 8, "carol": 9}
+	})
+	if len(failures) > 0 || len(tracker.Issues) != 2 {
+		t.Fatalf("ProcessIssueFiles() = %v, created %d issues, want 2", failures, len(tracker.Issues))
+	}
+	for index, want := range []struct {
+		name       string
+		assigneeId int
+		reference  string
+	}{{"Alice", 7, "report-alice"}, {"Bob", 8, "report-bob"}} {
+		issue := tracker.Issues[index]
+		reference, _, _ := occurrenceMarkers.ParseMarker(issue.Description)
+		if issue.Title != "Report of "+want.name || !strings.HasPrefix(issue.Description, "Hi "+want.name) ||
+			issue.Assignees[0].ID != want.assigneeId || reference != want.reference {
+			t.Errorf("ProcessIssueFiles() issue = %v assigned to %v for %v, want %v", issue.Title, issue.Assignees[0].ID, reference, want)
+		}
+	}
+	if _, exists := state.Occurrences["report.md#bob"]; !exists || len(state.Occurrences) != 2 {
+		t.Errorf("ProcessIssueFiles() state occurrences = %v, want one per created instance", state.Occurrences)
+	}
+}
+
+func TestReadRecurringIssueDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		template string
+		want     *types.Metadata
+		wantErr  bool
+	}{
+		{
+			name: "Uses defaults for unset fields",
+			files: map[string]string{
+				"_defaults.yml": "labels: [\"chore\"]\nconfidential: true\nduein: 24h\n",
+			},
+			template: "team/weekly.md",
+			want:     &types.Metadata{Title: "Weekly", Labels: []string{"chore", "weekly"}, Confidential: true, DueIn: "24h"},
+		},
+		{
+			name: "Overrides defaults with template fields",
+			files: map[string]string{
+				"_defaults.yml": "confidential: true\nduein: 24h\n",
+			},
+			template: "weekly.md",
+			want:     &types.Metadata{Title: "Weekly", Labels: []string{"weekly"}, DueIn: "1h"},
+		},
+		{
+			name: "Cascades defaults of nested directories",
+			files: map[string]string{
+				"_defaults.yml":      "labels: [\"chore\"]\nduein: 24h\n",
+				"team/_defaults.yml": "labels: [\"team\"]\nduein: 48h\n",
+			},
+			template: "team/weekly.md",
+			want:     &types.Metadata{Title: "Weekly", Labels: []string{"chore", "team", "weekly"}, DueIn: "48h"},
+		},
+		{
+			name: "Replaces lists if configured",
+			files: map[string]string{
+				"_defaults.yml":      "labels: [\"chore\"]\n",
+				"team/_defaults.yml": "labels: [\"team\"]\nlistMerge: replace\n",
+			},
+			template: "team/weekly.md",
+			want:     &types.Metadata{Title: "Weekly", Labels: []string{"weekly"}, ListMerge: "replace"},
+		},
+		{
+			name: "Fails for unknown list merge",
+			files: map[string]string{
+				"_defaults.yml": "listMerge: merge\n",
+			},
+			template: "weekly.md",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CI_PROJECT_DIR", t.TempDir())
+			for name, contents := range tt.files {
+				writeTemplate(t, name, contents)
+			}
+			ownFields := map[string]string{
+				"weekly.md":      "confidential: false\nduein: 1h\n",
+				"team/weekly.md": "",
+			}
+			writeTemplate(t, tt.template, "---\ntitle: Weekly\nlabels: [\"weekly\"]\n"+ownFields[tt.template]+"crontab: \"@weekly\"\n---\nDo the chore")
+			got, err := readRecurringIssue(filepath.Join(gitlabUtils.GetRecurringIssuesPath(), tt.template))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readRecurringIssue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Title != tt.want.Title || got.Description != "Do the chore" || got.Confidential != tt.want.Confidential ||
+				got.DueIn != tt.want.DueIn || got.ListMerge != tt.want.ListMerge || !reflect.DeepEqual(got.Labels, tt.want.Labels) {
+				t.Errorf("readRecurringIssue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
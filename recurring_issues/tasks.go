@@ -0,0 +1,78 @@
+package recurringIssues
+
+import (
+	"fmt"
+	"gitlab-issue-automation/constants"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	occurrenceMarkers "gitlab-issue-automation/occurrence_markers"
+	placeholders "gitlab-issue-automation/placeholders"
+	types "gitlab-issue-automation/types"
+	"log"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// Tasks share the occurrence of the recurring issue, their markers reference
+// the task so that they are not mistaken for the recurring issue
+func getTaskOccurrence(data *types.Metadata, task types.Task, index int, parentIssueId int) (*types.Metadata, error) {
+	if task.Title == "" {
+		return nil, fmt.Errorf("task %d: missing title", index+1)
+	}
+	taskOccurrence := &types.Metadata{
+		Title:        task.Title,
+		Id:           fmt.Sprintf("%s#task-%d", occurrenceMarkers.GetTemplateReference(data), index+1),
+		Description:  fmt.Sprintf("Task of #%d", parentIssueId),
+		Confidential: data.Confidential,
+		Labels:       append([]string{}, task.Labels...),
+		DueIn:        task.DueIn,
+		Location:     data.Location,
+		TemplateKey:  data.TemplateKey,
+		NextTime:     data.NextTime,
+		Variables:    data.Variables,
+	}
+	if task.Assignee != "" {
+		taskOccurrence.Assignees = []string{task.Assignee}
+	}
+	return placeholders.ApplyPlaceholders(taskOccurrence)
+}
+
+func validateTasks(data *types.Metadata) error {
+	for index, task := range data.Tasks {
+		taskOccurrence, err := getTaskOccurrence(data, task, index, 0)
+		if err != nil {
+			return err
+		}
+		if taskOccurrence.DueIn != "" {
+			_, err = gitlabUtils.GetIssueDueDate(taskOccurrence)
+			if err != nil {
+				return fmt.Errorf("task %d: %w", index+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Creates the tasks of the template as issues related to the recurring issue
+func createTasks(data *types.Metadata, issue *gitlab.Issue) error {
+	for index, task := range data.Tasks {
+		taskOccurrence, err := getTaskOccurrence(data, task, index, issue.IID)
+		if err != nil {
+			return err
+		}
+		err = createTask(taskOccurrence, issue)
+		if err != nil {
+			return fmt.Errorf("task %d: %w", index+1, err)
+		}
+	}
+	return nil
+}
+
+func createTask(taskOccurrence *types.Metadata, issue *gitlab.Issue) error {
+	log.Println("-- Creating task", taskOccurrence.Title)
+	taskIssue, existed, err := gitlabUtils.CreateIssue(taskOccurrence)
+	if err != nil {
+		return err
+	}
+	_, err = gitlabUtils.CreateMissingIssueLink(taskIssue.IID, issue.IID, constants.RelatesToLinkType, existed)
+	return err
+}
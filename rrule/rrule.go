@@ -0,0 +1,445 @@
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	dateUtils "gitlab-issue-automation/date_utils"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	daily   = "DAILY"
+	weekly  = "WEEKLY"
+	monthly = "MONTHLY"
+	yearly  = "YEARLY"
+)
+
+// Periods that are checked for an occurrence before giving up, e.g. for rules
+// like every February 30
+const maxPeriods = 10000
+
+var weekdayNames = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// A weekday of BYDAY, e.g. 2TU for the second or -1FR for the last Friday of
+// the month, the ordinal is 0 for every such weekday
+type weekday struct {
+	ordinal int
+	day     time.Weekday
+}
+
+// Rule is a recurrence rule of RFC 5545 with the parts FREQ, INTERVAL, BYDAY,
+// BYMONTHDAY, BYMONTH, BYSETPOS, UNTIL, and COUNT. Occurrences start at
+// DTSTART and have its time of day. Weeks start on Monday.
+type Rule struct {
+	frequency    string
+	start        time.Time
+	interval     int
+	weekdays     []weekday
+	monthDays    []int
+	months       []time.Month
+	setPositions []int
+	until        time.Time
+	count        int
+}
+
+// Parses a rule like
+//
+//	DTSTART:20260105T090000
+//	RRULE:FREQ=WEEKLY;INTERVAL=3;BYDAY=MO
+//
+// Times without Z or TZID are in the given location.
+func Parse(text string, location *time.Location) (*Rule, error) {
+	rule := &Rule{interval: 1}
+	var ruleParts string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "DTSTART"):
+			start, err := parseStart(line, location)
+			if err != nil {
+				return nil, err
+			}
+			rule.start = start
+		case strings.HasPrefix(line, "RRULE:"):
+			ruleParts = strings.TrimPrefix(line, "RRULE:")
+		case strings.HasPrefix(line, "FREQ=") || strings.Contains(line, ";FREQ="):
+			ruleParts = line
+		default:
+			return nil, fmt.Errorf("unknown rrule line '%s', use DTSTART and RRULE", line)
+		}
+	}
+	if rule.start.IsZero() {
+		return nil, errors.New("rrule is missing DTSTART")
+	}
+	if ruleParts == "" {
+		return nil, errors.New("rrule is missing RRULE")
+	}
+	for _, part := range strings.Split(ruleParts, ";") {
+		name, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid rrule part '%s'", part)
+		}
+		err := rule.setPart(name, value, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rrule part %s: %w", name, err)
+		}
+	}
+	return rule, rule.validate()
+}
+
+func parseStart(line string, location *time.Location) (time.Time, error) {
+	parameters, value, found := strings.Cut(line, ":")
+	if !found {
+		return time.Time{}, fmt.Errorf("invalid DTSTART '%s'", line)
+	}
+	for _, parameter := range strings.Split(parameters, ";")[1:] {
+		name, timezone, _ := strings.Cut(parameter, "=")
+		if name != "TZID" {
+			return time.Time{}, fmt.Errorf("unknown DTSTART parameter '%s'", parameter)
+		}
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown timezone '%s'", timezone)
+		}
+	}
+	start, err := dateUtils.ParseICalendarTime(value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid DTSTART: %w", err)
+	}
+	return start, nil
+}
+
+func parseNumbers(value string, minimum int, maximum int) ([]int, error) {
+	numbers := []int{}
+	for _, item := range strings.Split(value, ",") {
+		number, err := strconv.Atoi(strings.TrimPrefix(item, "+"))
+		if err != nil || number == 0 || number < minimum || number > maximum {
+			return nil, fmt.Errorf("invalid number '%s'", item)
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+func parseWeekdays(value string) ([]weekday, error) {
+	weekdays := []weekday{}
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid weekday '%s'", item)
+		}
+		day, exists := weekdayNames[item[len(item)-2:]]
+		if !exists {
+			return nil, fmt.Errorf("invalid weekday '%s'", item)
+		}
+		ordinal := 0
+		if len(item) > 2 {
+			ordinals, err := parseNumbers(item[:len(item)-2], -53, 53)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weekday '%s'", item)
+			}
+			ordinal = ordinals[0]
+		}
+		weekdays = append(weekdays, weekday{ordinal, day})
+	}
+	return weekdays, nil
+}
+
+func (r *Rule) setPart(name string, value string, location *time.Location) error {
+	var err error
+	switch name {
+	case "FREQ":
+		switch value {
+		case daily, weekly, monthly, yearly:
+			r.frequency = value
+		default:
+			return fmt.Errorf("unsupported frequency '%s', use DAILY, WEEKLY, MONTHLY, or YEARLY", value)
+		}
+	case "INTERVAL":
+		var intervals []int
+		intervals, err = parseNumbers(value, 1, 10000)
+		if err == nil {
+			r.interval = intervals[0]
+		}
+	case "COUNT":
+		var counts []int
+		counts, err = parseNumbers(value, 1, 1000000)
+		if err == nil {
+			r.count = counts[0]
+		}
+	case "UNTIL":
+		r.until, err = dateUtils.ParseICalendarTime(value, location)
+		if err == nil && len(value) == len(dateUtils.ICalendarDateLayout) {
+			// Dates include the whole day
+			r.until = r.until.AddDate(0, 0, 1).Add(-time.Second)
+		}
+	case "BYDAY":
+		r.weekdays, err = parseWeekdays(value)
+	case "BYMONTHDAY":
+		r.monthDays, err = parseNumbers(value, -31, 31)
+	case "BYMONTH":
+		var months []int
+		months, err = parseNumbers(value, 1, 12)
+		for _, month := range months {
+			r.months = append(r.months, time.Month(month))
+		}
+	case "BYSETPOS":
+		r.setPositions, err = parseNumbers(value, -366, 366)
+	case "WKST":
+		if value != "MO" {
+			err = errors.New("only weeks starting on MO are supported")
+		}
+	default:
+		err = errors.New("unsupported part, use FREQ, INTERVAL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS, UNTIL, or COUNT")
+	}
+	return err
+}
+
+func (r *Rule) validate() error {
+	if r.frequency == "" {
+		return errors.New("rrule is missing FREQ")
+	}
+	if r.count > 0 && !r.until.IsZero() {
+		return errors.New("rrule cannot have both COUNT and UNTIL")
+	}
+	for _, day := range r.weekdays {
+		if day.ordinal != 0 && r.frequency != monthly && r.frequency != yearly {
+			return errors.New("rrule BYDAY can only have ordinals for MONTHLY and YEARLY")
+		}
+	}
+	if len(r.setPositions) > 0 && len(r.weekdays) == 0 && len(r.monthDays) == 0 && len(r.months) == 0 {
+		return errors.New("rrule BYSETPOS needs BYDAY, BYMONTHDAY, or BYMONTH")
+	}
+	return nil
+}
+
+// Returns the first occurrence after the given time, or the zero time if the
+// rule has no more occurrences
+func (r *Rule) Next(fromTime time.Time) time.Time {
+	count := 0
+	firstPeriod := 0
+	if r.count == 0 {
+		// Without COUNT, the occurrences before the time need not be counted
+		firstPeriod = r.getPeriodsBefore(fromTime)
+	}
+	for period := firstPeriod; period < firstPeriod+maxPeriods; period++ {
+		for _, occurrence := range r.getPeriodOccurrences(period) {
+			if occurrence.Before(r.start) {
+				continue
+			}
+			if !r.until.IsZero() && occurrence.After(r.until) {
+				return time.Time{}
+			}
+			count++
+			if r.count > 0 && count > r.count {
+				return time.Time{}
+			}
+			if occurrence.After(fromTime) {
+				return occurrence
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// Number of whole periods between the start and the given time, minus one to
+// be safe around daylight saving time changes
+func (r *Rule) getPeriodsBefore(fromTime time.Time) int {
+	if !fromTime.After(r.start) {
+		return 0
+	}
+	fromTime = fromTime.In(r.start.Location())
+	var periods int
+	switch r.frequency {
+	case daily:
+		periods = int(fromTime.Sub(r.start).Hours() / 24)
+	case weekly:
+		periods = int(fromTime.Sub(r.start).Hours() / 24 / 7)
+	case monthly:
+		periods = (fromTime.Year()-r.start.Year())*12 + int(fromTime.Month()-r.start.Month())
+	case yearly:
+		periods = fromTime.Year() - r.start.Year()
+	}
+	periods = periods/r.interval - 1
+	if periods < 0 {
+		return 0
+	}
+	return periods
+}
+
+func getDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func getDaysInMonth(year int, month time.Month) int {
+	return getDate(year, month+1, 0).Day()
+}
+
+func containsMonth(months []time.Month, month time.Month) bool {
+	for _, candidate := range months {
+		if candidate == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonthDay(date time.Time) bool {
+	daysInMonth := getDaysInMonth(date.Year(), date.Month())
+	for _, monthDay := range r.monthDays {
+		if monthDay == date.Day() || daysInMonth+monthDay+1 == date.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesWeekday(date time.Time) bool {
+	for _, day := range r.weekdays {
+		if day.day == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// Days of the range from first to last (inclusive) with the weekday, or only
+// the n-th of them for ordinals
+func getWeekdays(day weekday, first time.Time, last time.Time) []time.Time {
+	dates := []time.Time{}
+	for date := first; !date.After(last); date = date.AddDate(0, 0, 1) {
+		if date.Weekday() == day.day {
+			dates = append(dates, date)
+		}
+	}
+	if day.ordinal == 0 {
+		return dates
+	}
+	index := day.ordinal - 1
+	if day.ordinal < 0 {
+		index = len(dates) + day.ordinal
+	}
+	if index < 0 || index >= len(dates) {
+		return []time.Time{}
+	}
+	return dates[index : index+1]
+}
+
+// Days of the month that match BYMONTHDAY and BYDAY, or the day of the start
+func (r *Rule) getMonthDates(year int, month time.Month) []time.Time {
+	first := getDate(year, month, 1)
+	last := getDate(year, month, getDaysInMonth(year, month))
+	dates := []time.Time{}
+	switch {
+	case len(r.monthDays) > 0:
+		for date := first; !date.After(last); date = date.AddDate(0, 0, 1) {
+			if r.matchesMonthDay(date) && (len(r.weekdays) == 0 || r.matchesWeekday(date)) {
+				dates = append(dates, date)
+			}
+		}
+	case len(r.weekdays) > 0:
+		for _, day := range r.weekdays {
+			dates = append(dates, getWeekdays(day, first, last)...)
+		}
+	case r.start.Day() <= last.Day():
+		dates = append(dates, getDate(year, month, r.start.Day()))
+	}
+	return dates
+}
+
+func (r *Rule) getPeriodDates(period int) []time.Time {
+	startDate := getDate(r.start.Year(), r.start.Month(), r.start.Day())
+	dates := []time.Time{}
+	switch r.frequency {
+	case daily:
+		date := startDate.AddDate(0, 0, period*r.interval)
+		if (len(r.months) == 0 || containsMonth(r.months, date.Month())) &&
+			(len(r.monthDays) == 0 || r.matchesMonthDay(date)) &&
+			(len(r.weekdays) == 0 || r.matchesWeekday(date)) {
+			dates = append(dates, date)
+		}
+	case weekly:
+		weekStart := startDate.AddDate(0, 0, -(int(startDate.Weekday())+6)%7)
+		weekStart = weekStart.AddDate(0, 0, 7*period*r.interval)
+		for offset := 0; offset < 7; offset++ {
+			date := weekStart.AddDate(0, 0, offset)
+			matchesDay := date.Weekday() == r.start.Weekday()
+			if len(r.weekdays) > 0 {
+				matchesDay = r.matchesWeekday(date)
+			}
+			if matchesDay && (len(r.months) == 0 || containsMonth(r.months, date.Month())) {
+				dates = append(dates, date)
+			}
+		}
+	case monthly:
+		month := getDate(r.start.Year(), r.start.Month()+time.Month(period*r.interval), 1)
+		if len(r.months) == 0 || containsMonth(r.months, month.Month()) {
+			dates = r.getMonthDates(month.Year(), month.Month())
+		}
+	case yearly:
+		year := r.start.Year() + period*r.interval
+		if len(r.months) == 0 && len(r.monthDays) == 0 && len(r.weekdays) > 0 {
+			// Ordinals of BYDAY count within the year
+			for _, day := range r.weekdays {
+				dates = append(dates, getWeekdays(day, getDate(year, time.January, 1), getDate(year, time.December, 31))...)
+			}
+			break
+		}
+		months := r.months
+		if len(months) == 0 && len(r.monthDays) > 0 {
+			months = []time.Month{time.January, time.February, time.March, time.April, time.May, time.June,
+				time.July, time.August, time.September, time.October, time.November, time.December}
+		} else if len(months) == 0 {
+			months = []time.Month{r.start.Month()}
+		}
+		for _, month := range months {
+			dates = append(dates, r.getMonthDates(year, month)...)
+		}
+	}
+	return dates
+}
+
+// Occurrences of the period, sorted and limited to BYSETPOS
+func (r *Rule) getPeriodOccurrences(period int) []time.Time {
+	dates := r.getPeriodDates(period)
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+	uniqueDates := []time.Time{}
+	for _, date := range dates {
+		if len(uniqueDates) == 0 || !uniqueDates[len(uniqueDates)-1].Equal(date) {
+			uniqueDates = append(uniqueDates, date)
+		}
+	}
+	if len(r.setPositions) > 0 {
+		selectedDates := []time.Time{}
+		for index, date := range uniqueDates {
+			for _, position := range r.setPositions {
+				if position == index+1 || position == index-len(uniqueDates) {
+					selectedDates = append(selectedDates, date)
+					break
+				}
+			}
+		}
+		uniqueDates = selectedDates
+	}
+	occurrences := []time.Time{}
+	hour, minute, second := r.start.Clock()
+	for _, date := range uniqueDates {
+		occurrences = append(occurrences, time.Date(date.Year(), date.Month(), date.Day(), hour, minute, second, 0, r.start.Location()))
+	}
+	return occurrences
+}
@@ -0,0 +1,135 @@
+package rrule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		rule     string
+		fromTime time.Time
+		want     []string
+		// Whether the rule ends after the wanted occurrences
+		wantEnd bool
+	}{
+		{
+			name:     "Second Tuesday of every month",
+			rule:     "DTSTART:20260101T090000\nRRULE:FREQ=MONTHLY;BYDAY=2TU",
+			fromTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-01-13T09:00:00Z", "2026-02-10T09:00:00Z", "2026-03-10T09:00:00Z"},
+		},
+		{
+			name:     "Every 3 weeks from a start date",
+			rule:     "DTSTART:20260105T090000\nRRULE:FREQ=WEEKLY;INTERVAL=3;BYDAY=MO",
+			fromTime: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-03-09T09:00:00Z", "2026-03-30T09:00:00Z", "2026-04-20T09:00:00Z"},
+		},
+		{
+			name:     "Last workday of the month",
+			rule:     "DTSTART:20260101T160000\nRRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			fromTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-01-30T16:00:00Z", "2026-02-27T16:00:00Z", "2026-03-31T16:00:00Z"},
+		},
+		{
+			name:     "Last Friday of the month",
+			rule:     "DTSTART:20260101T090000\nRRULE:FREQ=MONTHLY;BYDAY=-1FR",
+			fromTime: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-04-24T09:00:00Z", "2026-05-29T09:00:00Z", "2026-06-26T09:00:00Z"},
+		},
+		{
+			name:     "Skips months without the day",
+			rule:     "DTSTART:20260131T090000\nRRULE:FREQ=MONTHLY",
+			fromTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-01-31T09:00:00Z", "2026-03-31T09:00:00Z", "2026-05-31T09:00:00Z"},
+		},
+		{
+			name:     "Yearly on a day of a month",
+			rule:     "DTSTART:20240229T090000\nRRULE:FREQ=YEARLY",
+			fromTime: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2028-02-29T09:00:00Z", "2032-02-29T09:00:00Z"},
+		},
+		{
+			name:     "Yearly on the first Monday of September",
+			rule:     "DTSTART:20260101T090000\nRRULE:FREQ=YEARLY;BYMONTH=9;BYDAY=1MO",
+			fromTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-09-07T09:00:00Z", "2027-09-06T09:00:00Z"},
+		},
+		{
+			name:     "Stops after COUNT occurrences",
+			rule:     "DTSTART:20260105T090000\nRRULE:FREQ=DAILY;INTERVAL=2;COUNT=3",
+			fromTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-01-05T09:00:00Z", "2026-01-07T09:00:00Z", "2026-01-09T09:00:00Z"},
+			wantEnd:  true,
+		},
+		{
+			name:     "Stops after UNTIL",
+			rule:     "DTSTART:20260105T090000\nRRULE:FREQ=WEEKLY;UNTIL=20260119",
+			fromTime: time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-01-12T09:00:00Z", "2026-01-19T09:00:00Z"},
+			wantEnd:  true,
+		},
+		{
+			name:     "Keeps the time of day in the timezone of DTSTART",
+			rule:     "DTSTART;TZID=Europe/Berlin:20260323T090000\nRRULE:FREQ=WEEKLY",
+			fromTime: time.Date(2026, 3, 24, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-03-30T07:00:00Z", "2026-04-06T07:00:00Z"},
+		},
+		{
+			name:     "Accepts rule parts without RRULE",
+			rule:     "DTSTART:20260105T090000Z\nFREQ=DAILY;BYDAY=SA,SU",
+			fromTime: time.Date(2026, 1, 5, 0, 0, 0, 0, berlin),
+			want:     []string{"2026-01-10T09:00:00Z", "2026-01-11T09:00:00Z", "2026-01-17T09:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			fromTime := tt.fromTime
+			for range tt.want {
+				fromTime = rule.Next(fromTime)
+				got = append(got, fromTime.UTC().Format(time.RFC3339))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+			if end := rule.Next(fromTime); end.IsZero() != tt.wantEnd {
+				t.Errorf("Next() after last occurrence = %v, want end %v", end, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{name: "Fails without DTSTART", rule: "RRULE:FREQ=DAILY"},
+		{name: "Fails without FREQ", rule: "DTSTART:20260105T090000\nRRULE:INTERVAL=2"},
+		{name: "Fails for unknown frequency", rule: "DTSTART:20260105T090000\nRRULE:FREQ=HOURLY"},
+		{name: "Fails for unknown parts", rule: "DTSTART:20260105T090000\nRRULE:FREQ=DAILY;BYHOUR=9"},
+		{name: "Fails for invalid weekdays", rule: "DTSTART:20260105T090000\nRRULE:FREQ=MONTHLY;BYDAY=2XY"},
+		{name: "Fails for ordinals of weekly rules", rule: "DTSTART:20260105T090000\nRRULE:FREQ=WEEKLY;BYDAY=2TU"},
+		{name: "Fails for COUNT and UNTIL", rule: "DTSTART:20260105T090000\nRRULE:FREQ=DAILY;COUNT=2;UNTIL=20260110"},
+		{name: "Fails for invalid DTSTART", rule: "DTSTART:2026-01-05\nRRULE:FREQ=DAILY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.rule, time.UTC)
+			if err == nil {
+				t.Errorf("Parse() should fail for %s", tt.rule)
+			}
+		})
+	}
+}
@@ -3,7 +3,7 @@ package standupNotes
 import (
 	"fmt"
 	boardLabels "gitlab-issue-automation/board_labels"
-	constants "gitlab-issue-automation/constants"
+	config "gitlab-issue-automation/config"
 	dateUtils "gitlab-issue-automation/date_utils"
 	gitlabUtils "gitlab-issue-automation/gitlab_utils"
 	recurringIssues "gitlab-issue-automation/recurring_issues"
@@ -17,31 +17,34 @@ import (
 	"github.com/xanzy/go-gitlab"
 )
 
-const StandupTitlePrefix = "Meetings/Standup/"
 const lookupStart = "2022-04-06"
 
-func getLastNoteDate(currentDate time.Time) time.Time {
-	wikiPages := gitlabUtils.GetWikiPagesMetadata()
-	latestStandup, err := time.Parse(dateUtils.ShortISODateLayout, lookupStart)
+func getLastNoteDate(currentDate time.Time) (time.Time, error) {
+	location := config.GetLocation()
+	latestStandup, err := time.ParseInLocation(dateUtils.ShortISODateLayout, lookupStart, location)
 	if err != nil {
-		log.Fatal(err)
+		return latestStandup, err
+	}
+	wikiPages, err := gitlabUtils.GetWikiPagesMetadata()
+	if err != nil {
+		return latestStandup, err
 	}
 	for _, wikiPage := range wikiPages {
-		if !strings.HasPrefix(wikiPage.Slug, StandupTitlePrefix) {
+		if !strings.HasPrefix(wikiPage.Slug, config.Get().StandupWikiPrefix) {
 			continue
 		}
 		if !dateUtils.IsDashedDate(wikiPage.Title) {
 			continue
 		}
-		thisStandupDate, err := time.Parse(dateUtils.ShortISODateLayout, dateUtils.UnescapeDashes(wikiPage.Title))
+		thisStandupDate, err := time.ParseInLocation(dateUtils.ShortISODateLayout, dateUtils.UnescapeDashes(wikiPage.Title), location)
 		if err != nil {
-			log.Fatal(err)
+			return latestStandup, err
 		}
 		if thisStandupDate.After(latestStandup) {
 			latestStandup = thisStandupDate
 		}
 	}
-	return latestStandup
+	return latestStandup, nil
 }
 
 func getComparableLabels(issue *gitlab.Issue) string {
@@ -65,18 +68,25 @@ func printIssue(issue *gitlab.Issue) string {
 	return issueString
 }
 
-func CreateNotes(noteDate time.Time) {
+func CreateNotes(noteDate time.Time) error {
 	noteName := dateUtils.GetEnDashDate(noteDate)
-	title := StandupTitlePrefix + noteName
+	title := config.Get().StandupWikiPrefix + noteName
 	if !gitlabUtils.WikiPageExists(title) {
-		lastNoteDate := getLastNoteDate(noteDate)
+		lastNoteDate, err := getLastNoteDate(noteDate)
+		if err != nil {
+			return err
+		}
 		orderBy := "updated_at"
 		sortOrder := "desc"
-		issues := gitlabUtils.GetSortedProjectIssues(orderBy, sortOrder, "")
+		issues, err := gitlabUtils.GetSortedProjectIssues(orderBy, sortOrder, "")
+		if err != nil {
+			return err
+		}
+		labels := config.Get().Labels
 		relevantIssues := []*gitlab.Issue{}
 		projects := []string{}
 		for _, issue := range issues {
-			if boardLabels.HasLabel(issue, constants.TestLabel) || boardLabels.HasLabel(issue, constants.RecurringLabel) {
+			if boardLabels.HasLabel(issue, labels.Test) || boardLabels.HasLabel(issue, labels.Recurring) {
 				continue
 			}
 			if issue.UpdatedAt.After(lastNoteDate) {
@@ -85,7 +95,7 @@ func CreateNotes(noteDate time.Time) {
 					projectLabels := []string{}
 					for _, label := range issue.Labels {
 						isNonProjectLabel := true
-						for _, nonProjectLabel := range constants.NonProjectLabels {
+						for _, nonProjectLabel := range config.Get().NonProjectLabels {
 							if label == nonProjectLabel {
 								isNonProjectLabel = false
 								break
@@ -129,34 +139,38 @@ func CreateNotes(noteDate time.Time) {
 			content += printIssue(issue)
 		}
 		log.Println("- Creating new wiki page", title)
-		gitlabUtils.CreateWikiPage(title, content)
-	} else {
-		log.Println("- Skipping creation of wiki page", title, "because it already exists")
+		return gitlabUtils.CreateWikiPage(title, content)
 	}
+	log.Println("- Skipping creation of wiki page", title, "because it already exists")
+	return nil
 }
 
-func WriteNotes(lastTime time.Time, forceStandupNotesForToday bool) {
-	if (forceStandupNotesForToday) {
+func WriteNotes(lastTime time.Time, forceStandupNotesForToday bool) error {
+	if forceStandupNotesForToday {
 		log.Println("- Forcing creating standup notes for today")
-		CreateNotes(time.Now())
-		return
+		return CreateNotes(time.Now().In(config.GetLocation()))
 	}
-	standupIssuePath := filepath.Join(gitlabUtils.GetRecurringIssuesPath(), constants.StandupIssueTemplateName)
+	standupIssuePath := filepath.Join(gitlabUtils.GetRecurringIssuesPath(), config.Get().StandupTemplateName)
 	_, err := os.Stat(standupIssuePath)
 	standupIssueExists := err == nil
 	if !standupIssueExists {
 		log.Println("- Skipping creation of standup notes because no issue exists")
-		return
+		return nil
 	}
 	verbose := false
-	standupIssue, err := recurringIssues.GetRecurringIssue(standupIssuePath, lastTime, verbose)
+	standupIssues, err := recurringIssues.GetRecurringIssue(standupIssuePath, lastTime, verbose)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	// Standup notes are written once, for the first instance of the template
+	standupIssue := standupIssues[0]
+	issueDue, err := gitlabUtils.GetIssueDueDate(standupIssue)
+	if err != nil {
+		return err
 	}
-	issueDue := gitlabUtils.GetIssueDueDate(standupIssue)
 	if dateUtils.AreDatesEqual(issueDue, time.Now()) {
-		CreateNotes(issueDue)
-	} else {
-		log.Println("- Skipping creation of standup notes because it is not due yet")
+		return CreateNotes(issueDue)
 	}
+	log.Println("- Skipping creation of standup notes because it is not due yet")
+	return nil
 }
@@ -0,0 +1,94 @@
+package standupNotes
+
+import (
+	constants "gitlab-issue-automation/constants"
+	dateUtils "gitlab-issue-automation/date_utils"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestCreateNotes(t *testing.T) {
+	noteDate := time.Now()
+	lastNoteDate := noteDate.AddDate(0, 0, -7)
+	title := constants.StandupWikiPrefix + dateUtils.GetEnDashDate(noteDate)
+	tests := []struct {
+		name        string
+		issues      []*gitlab.Issue
+		wikiPages   map[string]string
+		wantContent []string
+		wantMissing []string
+	}{
+		{
+			name: "Lists updated issues and their projects",
+			issues: []*gitlab.Issue{
+				{Title: "Write report", Labels: gitlab.Labels{"Project A", constants.TodayLabel}},
+				{Title: "Review", Labels: gitlab.Labels{"Project B"}},
+			},
+			wantContent: []string{
+				"| Project A |  |  |  |  |",
+				"| Project B |  |  |  |  |",
+				"📝 [#1 Write report]",
+				"📝 [#2 Review]",
+			},
+			wantMissing: []string{"| " + constants.TodayLabel + " |"},
+		},
+		{
+			name: "Skips recurring and test issues",
+			issues: []*gitlab.Issue{
+				{Title: "Weekly meeting", Labels: gitlab.Labels{constants.RecurringLabel}},
+				{Title: "Try something", Labels: gitlab.Labels{constants.TestLabel}},
+			},
+			wantMissing: []string{"Weekly meeting", "Try something"},
+		},
+		{
+			name: "Skips issues updated before last notes",
+			issues: []*gitlab.Issue{
+				{Title: "Old issue", UpdatedAt: gitlab.Ptr(lastNoteDate.AddDate(0, 0, -1))},
+			},
+			wikiPages:   map[string]string{constants.StandupWikiPrefix + dateUtils.GetEnDashDate(lastNoteDate): ""},
+			wantMissing: []string{"Old issue"},
+		},
+		{
+			name:        "Keeps existing notes",
+			issues:      []*gitlab.Issue{{Title: "New issue"}},
+			wikiPages:   map[string]string{title: "Existing notes"},
+			wantContent: []string{"Existing notes"},
+			wantMissing: []string{"New issue"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := gitlabUtils.NewMemoryTracker()
+			gitlabUtils.SetTracker(tracker)
+			defer gitlabUtils.SetTracker(nil)
+			for _, issue := range tt.issues {
+				tracker.AddIssue(issue)
+			}
+			for wikiTitle, content := range tt.wikiPages {
+				tracker.WikiPages[wikiTitle] = content
+			}
+			err := CreateNotes(noteDate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, exists := tracker.WikiPages[title]
+			if !exists {
+				t.Fatalf("CreateNotes() did not create wiki page %s", title)
+			}
+			for _, want := range tt.wantContent {
+				if !strings.Contains(content, want) {
+					t.Errorf("CreateNotes() content = %v, want %v", content, want)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(content, missing) {
+					t.Errorf("CreateNotes() content = %v, should not contain %v", content, missing)
+				}
+			}
+		})
+	}
+}
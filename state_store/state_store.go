@@ -0,0 +1,189 @@
+package stateStore
+
+import (
+	"encoding/json"
+	"fmt"
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	types "gitlab-issue-automation/types"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const FileStoreType = "file"
+const VariableStoreType = "variable"
+const SnippetStoreType = "snippet"
+const PipelineStoreType = "pipeline"
+
+// Store persists the time of the last successful run and the last occurrence
+// of each recurring issue template. Load returns nil if nothing is stored yet.
+type Store interface {
+	Load() (*types.RunState, error)
+	Save(state *types.RunState) error
+}
+
+type fileStore struct {
+	path string
+}
+
+func (s *fileStore) Load() (*types.RunState, error) {
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseState(string(content))
+}
+
+func (s *fileStore) Save(state *types.RunState) error {
+	content, err := serializeState(state)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(s.path), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, []byte(content), 0644)
+}
+
+type variableStore struct {
+	key string
+}
+
+func (s *variableStore) Load() (*types.RunState, error) {
+	content, err := gitlabUtils.GetTracker().GetProjectVariable(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return parseState(content)
+}
+
+func (s *variableStore) Save(state *types.RunState) error {
+	content, err := serializeState(state)
+	if err != nil {
+		return err
+	}
+	return gitlabUtils.GetTracker().SetProjectVariable(s.key, content)
+}
+
+type snippetStore struct {
+	title string
+}
+
+func (s *snippetStore) Load() (*types.RunState, error) {
+	content, err := gitlabUtils.GetTracker().GetSnippetContent(s.title)
+	if err != nil {
+		return nil, err
+	}
+	return parseState(content)
+}
+
+func (s *snippetStore) Save(state *types.RunState) error {
+	content, err := serializeState(state)
+	if err != nil {
+		return err
+	}
+	return gitlabUtils.GetTracker().SetSnippetContent(s.title, content)
+}
+
+// Derives the last run from the latest successful scheduled pipeline, the
+// run state cannot be saved
+type pipelineStore struct{}
+
+func (s *pipelineStore) Load() (*types.RunState, error) {
+	lastRunTime, err := gitlabUtils.GetLastRunTime()
+	if err != nil {
+		return nil, err
+	}
+	return &types.RunState{LastRunTime: lastRunTime, Occurrences: map[string]time.Time{}}, nil
+}
+
+func (s *pipelineStore) Save(state *types.RunState) error {
+	return nil
+}
+
+func parseState(content string) (*types.RunState, error) {
+	if content == "" {
+		return nil, nil
+	}
+	state := new(types.RunState)
+	err := json.Unmarshal([]byte(content), state)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse run state: %w", err)
+	}
+	if state.Occurrences == nil {
+		state.Occurrences = map[string]time.Time{}
+	}
+	return state, nil
+}
+
+func serializeState(state *types.RunState) (string, error) {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+func GetStore() (Store, error) {
+	storeType := gitlabUtils.GetStateStoreType()
+	switch storeType {
+	case FileStoreType:
+		return &fileStore{path: gitlabUtils.GetStateStoreFile()}, nil
+	case VariableStoreType:
+		return &variableStore{key: gitlabUtils.GetStateStoreVariable()}, nil
+	case SnippetStoreType:
+		return &snippetStore{title: gitlabUtils.GetStateStoreSnippet()}, nil
+	case PipelineStoreType, "":
+		return &pipelineStore{}, nil
+	}
+	return nil, fmt.Errorf("unknown state store '%s', use one of %s, %s, %s, or %s", storeType, FileStoreType, VariableStoreType, SnippetStoreType, PipelineStoreType)
+}
+
+func LoadState(store Store) (*types.RunState, error) {
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		return state, nil
+	}
+	if gitlabUtils.HasScheduledPipelineId() {
+		log.Println("No run state stored yet, falling back to the last scheduled pipeline")
+		fallbackStore := &pipelineStore{}
+		return fallbackStore.Load()
+	}
+	log.Println("No run state stored yet, starting from now")
+	return &types.RunState{LastRunTime: time.Now(), Occurrences: map[string]time.Time{}}, nil
+}
+
+func SaveState(store Store, state *types.RunState) error {
+	return store.Save(state)
+}
+
+// Returns the time from which a template or stage is run, which is earlier
+// than the last run time if it failed before
+func GetRetryTime(state *types.RunState, key string, lastTime time.Time) time.Time {
+	failedTime, exists := state.Failed[key]
+	if exists && failedTime.Before(lastTime) {
+		return failedTime
+	}
+	return lastTime
+}
+
+// Records that a template or stage failed for the given time, or clears it if
+// there is no error
+func SetResult(state *types.RunState, key string, lastTime time.Time, err error) {
+	if err == nil {
+		delete(state.Failed, key)
+		return
+	}
+	if state.Failed == nil {
+		state.Failed = map[string]time.Time{}
+	}
+	state.Failed[key] = lastTime
+}
@@ -0,0 +1,96 @@
+package stateStore
+
+import (
+	gitlabUtils "gitlab-issue-automation/gitlab_utils"
+	types "gitlab-issue-automation/types"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStores(t *testing.T) {
+	tests := []struct {
+		name      string
+		storeType string
+		env       map[string]string
+	}{
+		{name: "File store", storeType: FileStoreType},
+		{name: "File store with path", storeType: FileStoreType, env: map[string]string{"STATE_STORE_FILE": filepath.Join(t.TempDir(), "state.json")}},
+		{name: "Variable store", storeType: VariableStoreType},
+		{name: "Snippet store", storeType: SnippetStoreType, env: map[string]string{"STATE_STORE_SNIPPET": "state"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CI_PROJECT_DIR", t.TempDir())
+			t.Setenv("STATE_STORE", tt.storeType)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			gitlabUtils.SetTracker(gitlabUtils.NewMemoryTracker())
+			defer gitlabUtils.SetTracker(nil)
+			store, err := GetStore()
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, err := store.Load()
+			if err != nil || state != nil {
+				t.Fatalf("Load() = %v, %v, want nothing stored", state, err)
+			}
+			want := &types.RunState{
+				LastRunTime: time.Date(2024, 3, 5, 6, 0, 0, 0, time.UTC),
+				Occurrences: map[string]time.Time{"weekly.md": time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)},
+			}
+			err = SaveState(store, want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadState(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("LoadState() = %v, want %v", got, want)
+			}
+		})
+	}
+	t.Run("Fails for unknown store", func(t *testing.T) {
+		t.Setenv("STATE_STORE", "unknown")
+		_, err := GetStore()
+		if err == nil {
+			t.Error("GetStore() should fail for an unknown store")
+		}
+	})
+}
+
+func TestLoadStateFallback(t *testing.T) {
+	lastPipelineTime := time.Date(2024, 3, 5, 6, 0, 0, 0, time.UTC)
+	tracker := gitlabUtils.NewMemoryTracker()
+	tracker.SuccessfulRunTimes[42] = lastPipelineTime
+	gitlabUtils.SetTracker(tracker)
+	defer gitlabUtils.SetTracker(nil)
+	t.Setenv("STATE_STORE", VariableStoreType)
+
+	t.Run("Falls back to scheduled pipeline", func(t *testing.T) {
+		t.Setenv("RECURRING_TASKS_SCHEDULED_PIPELINE_ID", "42")
+		store, _ := GetStore()
+		state, err := LoadState(store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !state.LastRunTime.Equal(lastPipelineTime) {
+			t.Errorf("LoadState() last run = %v, want %v", state.LastRunTime, lastPipelineTime)
+		}
+	})
+	t.Run("Starts from now without scheduled pipeline", func(t *testing.T) {
+		t.Setenv("RECURRING_TASKS_SCHEDULED_PIPELINE_ID", "")
+		store, _ := GetStore()
+		state, err := LoadState(store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Since(state.LastRunTime) > time.Minute {
+			t.Errorf("LoadState() last run = %v, want now", state.LastRunTime)
+		}
+	})
+}
@@ -2,22 +2,72 @@ package issueTypes
 
 import (
 	"time"
-
-	"github.com/gorhill/cronexpr"
 )
 
 type Metadata struct {
-	Title            string   `yaml:"title"`
-	Id               string   `yaml:"id"`
-	Description      string   `fm:"content" yaml:"-"`
-	Confidential     bool     `yaml:"confidential"`
-	Assignees        []string `yaml:"assignees,flow"`
-	Labels           []string `yaml:"labels,flow"`
-	DueIn            string   `yaml:"duein"`
-	Crontab          string   `yaml:"crontab"`
-	WeeklyRecurrence int      `yaml:"weeklyRecurrence"`
-	NextTime         time.Time
-	CronExpression   cronexpr.Expression
+	Title             string   `yaml:"title"`
+	Id                string   `yaml:"id"`
+	Description       string   `fm:"content" yaml:"-"`
+	Confidential      bool     `yaml:"confidential"`
+	Assignees         []string `yaml:"assignees,flow"`
+	Labels            []string `yaml:"labels,flow"`
+	Milestone         string   `yaml:"milestone"`
+	Iteration         string   `yaml:"iteration"`
+	Weight            *int     `yaml:"weight"`
+	Estimate          string   `yaml:"estimate"`
+	IssueType         string   `yaml:"issueType"`
+	Epic              string   `yaml:"epic"`
+	DueIn             string   `yaml:"duein"`
+	Crontab           string   `yaml:"crontab"`
+	RRule             string   `yaml:"rrule"`
+	Shift             string   `yaml:"shift"`
+	WeeklyRecurrence  int      `yaml:"weeklyRecurrence"`
+	MonthlyRecurrence int      `yaml:"monthlyRecurrence"`
+	YearlyRecurrence  int      `yaml:"yearlyRecurrence"`
+	AnchorDate        string   `yaml:"anchorDate"`
+	CatchUp           string   `yaml:"catchUp"`
+	LinkPrevious      bool     `yaml:"linkPrevious"`
+	NotePrevious      bool     `yaml:"notePrevious"`
+	PreviousPolicy    string   `yaml:"previousOccurrence"`
+	CarryOver         bool     `yaml:"carryOver"`
+	Tasks             []Task   `yaml:"tasks"`
+	// Each instance is a map of placeholder variables with a required id
+	Instances   []map[string]string `yaml:"instances"`
+	Instance    string              `yaml:"-"`
+	TemplateId  string              `yaml:"-"`
+	Variables   map[string]string   `yaml:"-"`
+	ListMerge   string              `yaml:"listMerge"`
+	Timezone    string              `yaml:"timezone"`
+	Location    *time.Location      `yaml:"-"`
+	TemplateKey string              `yaml:"-"`
+	NextTime    time.Time
+	Schedule    Schedule `yaml:"-"`
+	// Occurrence from which every n-th week, month, or year is counted, see
+	// AnchorDate
+	Anchor time.Time `yaml:"-"`
+	// Workdays and exceptions are loaded once with the template, since they
+	// are checked for every occurrence
+	Calendar   Calendar              `yaml:"-"`
+	Exceptions []ExceptionDefinition `yaml:"-"`
+}
+
+// Schedule of a template given by crontab or rrule, which returns the zero
+// time if there is no occurrence after the given time
+type Schedule interface {
+	Next(fromTime time.Time) time.Time
+}
+
+// Workdays to which occurrences are shifted, see Shift
+type Calendar interface {
+	Shift(day time.Time, shift string) time.Time
+}
+
+// Tasks are created as separate issues together with the recurring issue
+type Task struct {
+	Title    string   `yaml:"title"`
+	Assignee string   `yaml:"assignee"`
+	Labels   []string `yaml:"labels,flow"`
+	DueIn    string   `yaml:"duein"`
 }
 
 type RecurranceExceptions struct {
@@ -29,6 +79,8 @@ type ExceptionDefinition struct {
 	Id    string `yaml:"id"`
 	Start string `yaml:"start"`
 	End   string `yaml:"end"`
+	// .ics file in the templates directory whose events become definitions
+	Calendar string `yaml:"calendar"`
 }
 
 type ExceptionRule struct {
@@ -40,3 +92,41 @@ type WikiMetadata struct {
 	Title string
 	Slug  string
 }
+
+type RunState struct {
+	LastRunTime time.Time            `json:"lastRunTime"`
+	Occurrences map[string]time.Time `json:"occurrences"`
+	// Last run times of templates and stages that failed, from which they are
+	// retried while the run time of all others advances
+	Failed map[string]time.Time `json:"failed,omitempty"`
+}
+
+// Project configuration read from .gitlab/issue-automation.yml, unset values
+// fall back to the defaults in constants
+type Config struct {
+	Labels              LabelConfig `yaml:"labels"`
+	StatusLabels        []string    `yaml:"statusLabels,flow"`
+	ProgressLabels      []string    `yaml:"progressLabels,flow"`
+	NonProjectLabels    []string    `yaml:"nonProjectLabels,flow"`
+	IssueTemplatePath   string      `yaml:"issueTemplatePath"`
+	StandupTemplateName string      `yaml:"standupTemplateName"`
+	StandupWikiPrefix   string      `yaml:"standupWikiPrefix"`
+	Timezone            string      `yaml:"timezone"`
+	Workweek            []string    `yaml:"workweek,flow"`
+}
+
+type LabelConfig struct {
+	ThisWeek       string `yaml:"thisWeek"`
+	Today          string `yaml:"today"`
+	InProgress     string `yaml:"inProgress"`
+	Waiting        string `yaml:"waiting"`
+	InOffice       string `yaml:"inOffice"`
+	Recurring      string `yaml:"recurring"`
+	NextActions    string `yaml:"nextActions"`
+	Somewhen       string `yaml:"somewhen"`
+	Test           string `yaml:"test"`
+	DoneThisWeek   string `yaml:"doneThisWeek"`
+	NotYet         string `yaml:"notYet"`
+	IssueReference string `yaml:"issueReference"`
+	Missed         string `yaml:"missed"`
+}